@@ -2,35 +2,77 @@ package fs
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
-	"github.com/bool64/ctxd"
 	"github.com/nhatthm/plugin-registry/plugin"
 	"github.com/spf13/afero"
 )
 
+// ErrNoArtifact indicates that there is no installable artifact.
+var ErrNoArtifact = errors.New("no installable artifact")
+
 // ArchiveInstaller is an installer for archive file.
 type ArchiveInstaller struct {
-	fs afero.Fs
+	config
 
 	parseURL func(fs afero.Fs, pluginURL string) (path string, metadataPath string, err error)
-	install  func(fs afero.Fs, dst string, p plugin.Plugin, archiveFile string) error
+	install  func(ctx context.Context, cfg *config, dst string, p plugin.Plugin, archiveFile string) error
 }
 
 // Install installs the plugin.
 func (i *ArchiveInstaller) Install(ctx context.Context, dest, pluginURL string) (*plugin.Plugin, error) {
-	path, metadataPath, err := i.parseURL(i.fs, pluginURL)
-	if err != nil {
-		return nil, ctxd.WrapError(ctx, err, "could not parse plugin path", "path", pluginURL)
-	}
+	var (
+		path, metadataPath string
+		m                  *metadata
+	)
+
+	return i.observeInstall(ctx, func(ctx context.Context) (*plugin.Plugin, error) {
+		var err error
+
+		path, metadataPath, err = i.parseURL(i.srcFs, pluginURL)
+		if err != nil {
+			return nil, installError(ctx, PhaseDetect, pluginURL, err, "could not parse plugin path", "path", pluginURL)
+		}
+
+		p, err := plugin.Load(i.srcFs, metadataPath)
+		if err != nil {
+			return nil, installError(ctx, PhaseParse, pluginURL, err, "")
+		}
+
+		m, err = loadMetadata(i.srcFs, metadataPath)
+		if err != nil {
+			return nil, installError(ctx, PhaseParse, pluginURL, err, "could not read metadata", "path", metadataPath)
+		}
+
+		if i.version != "" {
+			p.Version = i.version
+		}
+
+		i.log(ctx).Debug(ctx, "plugin detected", "plugin", p.Name, "path", path)
+
+		return p, nil
+	}, func(ctx context.Context, p *plugin.Plugin) error {
+		cfg, err := i.forPlugin(m)
+		if err != nil {
+			return installError(ctx, PhaseParse, pluginURL, err, "invalid file filter", "path", metadataPath)
+		}
+
+		return cfg.run(ctx, dest, pluginURL, path, p, func(ctx context.Context, cfg *config, dest string) error {
+			return i.install(ctx, cfg, dest, *p, path)
+		})
+	})
+}
 
-	p, err := plugin.Load(i.fs, metadataPath)
-	if err != nil {
-		return nil, err
+// newArchiveInstallerFor returns the zip or gzip installer that supports the archive.
+func newArchiveInstallerFor(fs afero.Fs, path string, opts ...Option) (*ArchiveInstaller, error) {
+	if _, _, err := parseZipPath(fs, path); err == nil {
+		return NewZipInstaller(fs, opts...), nil
 	}
 
-	if err := i.install(i.fs, dest, *p, path); err != nil {
-		return nil, ctxd.WrapError(ctx, err, "could not install plugin", "path", path)
+	if _, _, err := parseGzipPath(fs, path); err == nil {
+		return NewGzipInstaller(fs, opts...), nil
 	}
 
-	return p, nil
+	return nil, fmt.Errorf("%s: %w", path, ErrNoArtifact)
 }
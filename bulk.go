@@ -0,0 +1,319 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+// Bulk installs many plugins concurrently.
+type Bulk struct {
+	fs      afero.Fs
+	workers int
+	opts    []Option
+}
+
+// BulkResult is the result of installing a source.
+type BulkResult struct {
+	Source string
+	// Plugin is the installed plugin, or the first one for a directory that has several plugins.
+	Plugin *plugin.Plugin
+	// Plugins are all the plugins installed from the source.
+	Plugins []*plugin.Plugin
+	Err     error
+}
+
+// BulkError aggregates the failures of a bulk installation.
+type BulkError struct {
+	Failures []BulkResult
+}
+
+// Error satisfies the error interface.
+func (e *BulkError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+
+	for _, r := range e.Failures {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", r.Source, r.Err))
+	}
+
+	return fmt.Sprintf("could not install %d plugin(s): %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// Is satisfies errors.Is, it checks the errors of the failures.
+func (e *BulkError) Is(target error) bool {
+	for _, r := range e.Failures {
+		if errors.Is(r.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As satisfies errors.As, it finds the first error of the failures that matches the target.
+func (e *BulkError) As(target interface{}) bool {
+	for _, r := range e.Failures {
+		if errors.As(r.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewBulk creates a new bulk installer that runs at most the given number of installations at the same time, or the
+// number of CPUs if it is not positive. The options are passed to the installers.
+func NewBulk(fs afero.Fs, workers int, opts ...Option) *Bulk {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &Bulk{
+		fs:      fs,
+		workers: workers,
+		opts:    opts,
+	}
+}
+
+// Glob finds the paths matching the doublestar patterns, for example "/src/*/my-*.zip".
+func (b *Bulk) Glob(patterns ...string) ([]string, error) {
+	var result []string
+
+	seen := make(map[string]struct{})
+
+	for _, pattern := range patterns {
+		matches, err := glob(b.fs, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+
+		for _, m := range matches {
+			if _, ok := seen[m]; !ok {
+				seen[m] = struct{}{}
+				result = append(result, m)
+			}
+		}
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// Scan walks the root directory recursively and finds every fs, zip or gzip source, and the directories that have
+// several plugins in a "plugins" list. The folder plugins are not walked into. In a directory of versioned archives,
+// only the latest version of the runtime artifact is used, otherwise every zip or gzip archive next to a
+// .plugin.registry.yaml is. A directory that has a .plugin.registry.yaml but no source is returned as is, so installing
+// it reports why it is not supported.
+func (b *Bulk) Scan(root string) ([]string, error) {
+	var result []string
+
+	err := afero.Walk(b.fs, root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() || !hasMetadata(b.fs, path) {
+			return nil
+		}
+
+		if _, _, err := parseFsPlugin(b.fs, path); err == nil || isMultiPlugin(b.fs, path) {
+			result = append(result, path)
+
+			return filepath.SkipDir
+		}
+
+		archives, err := b.archives(path)
+		if err != nil {
+			return err
+		}
+
+		if len(archives) == 0 {
+			archives = []string{path}
+		}
+
+		result = append(result, archives...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// archives finds the latest version of the runtime artifact in the directory, or every zip or gzip archive in it if
+// there is none.
+func (b *Bulk) archives(dir string) ([]string, error) {
+	if artifact, err := runtimeArtifact(b.fs, dir); err == nil {
+		if _, err := newArchiveInstallerFor(b.fs, artifact); err == nil {
+			return []string{artifact}, nil
+		}
+	}
+
+	files, err := afero.ReadDir(b.fs, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name())
+
+		if f.IsDir() {
+			continue
+		}
+
+		if _, err := newArchiveInstallerFor(b.fs, path); err == nil {
+			result = append(result, path)
+		}
+	}
+
+	return result, nil
+}
+
+// InstallGlob installs every source matching the doublestar patterns. See Bulk.Install.
+func (b *Bulk) InstallGlob(ctx context.Context, dest string, patterns ...string) ([]BulkResult, error) {
+	sources, err := b.Glob(patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Install(ctx, dest, sources)
+}
+
+// InstallScan installs every source found in the root directory. See Bulk.Scan and Bulk.Install.
+func (b *Bulk) InstallScan(ctx context.Context, dest, root string) ([]BulkResult, error) {
+	sources, err := b.Scan(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Install(ctx, dest, sources)
+}
+
+// Install installs the sources concurrently, the installer of each source is detected. It returns one result per
+// source in the same order, and a BulkError if any of them fails.
+func (b *Bulk) Install(ctx context.Context, dest string, sources []string) ([]BulkResult, error) {
+	results := make([]BulkResult, len(sources))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < b.workers && w < len(sources); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				results[j] = b.install(ctx, dest, sources[j])
+			}
+		}()
+	}
+
+	for j := range sources {
+		jobs <- j
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	var failures []BulkResult
+
+	for _, r := range results {
+		if r.Err != nil {
+			failures = append(failures, r)
+		}
+	}
+
+	if len(failures) > 0 {
+		return results, &BulkError{Failures: failures}
+	}
+
+	return results, nil
+}
+
+func (b *Bulk) install(ctx context.Context, dest, source string) BulkResult {
+	r := BulkResult{Source: source}
+
+	if err := ctx.Err(); err != nil {
+		r.Err = err
+
+		return r
+	}
+
+	if isMultiPlugin(b.fs, source) {
+		r.Plugins, r.Err = NewFsInstaller(b.fs, b.opts...).InstallAll(ctx, dest, source)
+
+		if len(r.Plugins) > 0 {
+			r.Plugin = r.Plugins[0]
+		}
+
+		return r
+	}
+
+	i, err := newInstallerFor(b.fs, source, b.opts...)
+	if err != nil {
+		r.Err = &InstallError{Phase: PhaseDetect, Source: source, Err: err}
+
+		return r
+	}
+
+	r.Plugin, r.Err = i.Install(ctx, dest, source)
+
+	if r.Plugin != nil {
+		r.Plugins = []*plugin.Plugin{r.Plugin}
+	}
+
+	return r
+}
+
+// runtimeArtifact finds the latest version of the runtime artifact in the directory.
+func runtimeArtifact(fs afero.Fs, dir string) (string, error) {
+	path, _, err := ResolveArtifact(fs, dir, latestVersion)
+	if err == nil || !errors.Is(err, ErrNoVersionInTemplate) {
+		return path, err
+	}
+
+	p, err := plugin.Load(fs, dir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, p.ResolveArtifact(p.RuntimeArtifact()).File), nil
+}
+
+// glob finds the paths matching the pattern in the file system. Absolute patterns are supported.
+func glob(fs afero.Fs, pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	root := ""
+
+	if strings.HasPrefix(pattern, "/") {
+		root = "/"
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+
+	matches, err := doublestar.Glob(afero.NewIOFS(afero.NewBasePathFs(fs, root+".")), pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range matches {
+		matches[i] = filepath.Join(root, filepath.FromSlash(m))
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,227 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/nhatthm/plugin-registry/installer"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBulkFs(t *testing.T) afero.Fs {
+	t.Helper()
+
+	platform := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+
+	fs := newMultiPluginFs(t, map[string]string{
+		"/opt/plugin-a/.plugin.registry.yaml":                 "name: plugin-a",
+		"/opt/plugin-a/plugin-a/plugin-a":                     "#!/bin/bash\n",
+		"/opt/nested/plugin-b/.plugin.registry.yaml":          "name: plugin-b",
+		"/opt/nested/plugin-b/plugin-b/plugin-b":              "#!/bin/bash\n",
+		"/opt/nested/plugin-b/plugin-b/.plugin.registry.yaml": "name: not-a-source",
+		"/opt/archives/.plugin.registry.yaml":                 "name: plugin-c",
+		"/opt/empty/.plugin.registry.yaml":                    "name: plugin-d",
+		"/opt/loose/.plugin.registry.yaml":                    "name: plugin-g",
+		"/opt/readme.md":                                      "",
+		"/opt/suite/.plugin.registry.yaml":                    "plugins:\n  - name: tool-e\n  - name: tool-f\n",
+		"/opt/suite/tool-e/tool-e":                            "#!/bin/bash\n",
+		"/opt/suite/tool-f/tool-f":                            "#!/bin/bash\n",
+	})
+
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		content := newTarGz(t, archiveEntry{name: "plugin-c/plugin-c", content: "#!/bin/bash\n", mode: 0o755})
+
+		require.NoError(t, afero.WriteFile(fs, fmt.Sprintf("/opt/archives/plugin-c-%s-%s.tar.gz", v, platform), content, 0o644))
+	}
+
+	// An archive that does not match the artifact template.
+	content := newZip(t, archiveEntry{name: "plugin-g/plugin-g", content: "#!/bin/bash\n", mode: 0o755})
+
+	require.NoError(t, afero.WriteFile(fs, "/opt/loose/plugin-g.zip", content, 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/opt/loose/notes.txt", nil, 0o644))
+
+	return fs
+}
+
+func TestBulk_Glob(t *testing.T) {
+	t.Parallel()
+
+	platform := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+
+	testCases := []struct {
+		scenario      string
+		patterns      []string
+		expected      []string
+		expectedError string
+	}{
+		{
+			scenario: "no match",
+			patterns: []string{"/src/*"},
+		},
+		{
+			scenario: "single pattern",
+			patterns: []string{"/opt/archives/plugin-c-*.tar.gz"},
+			expected: []string{
+				"/opt/archives/plugin-c-1.0.0-" + platform + ".tar.gz",
+				"/opt/archives/plugin-c-1.1.0-" + platform + ".tar.gz",
+			},
+		},
+		{
+			scenario: "double star",
+			patterns: []string{"/opt/**/plugin-?", "/opt/plugin-a"},
+			expected: []string{
+				"/opt/nested/plugin-b",
+				"/opt/nested/plugin-b/plugin-b",
+				"/opt/nested/plugin-b/plugin-b/plugin-b",
+				"/opt/plugin-a",
+				"/opt/plugin-a/plugin-a",
+				"/opt/plugin-a/plugin-a/plugin-a",
+			},
+		},
+		{
+			scenario:      "bad pattern",
+			patterns:      []string{"/opt/[a"},
+			expectedError: "/opt/[a: syntax error in pattern",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			paths, err := NewBulk(newBulkFs(t), 0).Glob(tc.patterns...)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+}
+
+func TestBulk_Scan(t *testing.T) {
+	t.Parallel()
+
+	paths, err := NewBulk(newBulkFs(t), 0).Scan("/opt")
+	require.NoError(t, err)
+
+	expected := []string{
+		fmt.Sprintf("/opt/archives/plugin-c-1.1.0-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH),
+		// The directories without a source are reported when they are installed.
+		"/opt/empty",
+		"/opt/loose/plugin-g.zip",
+		"/opt/nested/plugin-b",
+		"/opt/plugin-a",
+		"/opt/suite",
+	}
+
+	assert.Equal(t, expected, paths)
+}
+
+func TestBulk_Scan_Fixtures(t *testing.T) {
+	t.Parallel()
+
+	paths, err := NewBulk(afero.NewOsFs(), 0).Scan("resources/fixtures")
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		"resources/fixtures/fs/file",
+		"resources/fixtures/fs/folder",
+		"resources/fixtures/gzip/my-plugin.tar.gz",
+		"resources/fixtures/zip/my-plugin.zip",
+	} {
+		assert.Contains(t, paths, path)
+	}
+}
+
+func TestBulk_Scan_NotExist(t *testing.T) {
+	t.Parallel()
+
+	paths, err := NewBulk(afero.NewMemMapFs(), 0).Scan("/opt")
+
+	assert.Empty(t, paths)
+	require.EqualError(t, err, "open /opt: file does not exist")
+}
+
+func TestBulk_InstallScan(t *testing.T) {
+	t.Parallel()
+
+	fs := newBulkFs(t)
+
+	results, err := NewBulk(fs, 2).InstallScan(context.Background(), "/plugins", "/opt")
+	require.Len(t, results, 6)
+
+	var detectErr *DetectionError
+
+	require.True(t, errors.As(err, &detectErr))
+	assert.Equal(t, "/opt/empty", detectErr.Path)
+	assert.Equal(t, "/opt/empty", results[1].Source)
+	assert.ErrorIs(t, results[1].Err, installer.ErrNoInstaller)
+
+	for i, name := range map[int]string{0: "plugin-c", 2: "plugin-g", 3: "plugin-b", 4: "plugin-a", 5: "tool-e"} {
+		assert.NoError(t, results[i].Err)
+		assert.Equal(t, name, results[i].Plugin.Name)
+	}
+
+	assert.Equal(t, []string{"tool-e", "tool-f"}, pluginNames(results[5].Plugins))
+
+	for _, name := range []string{"plugin-c", "plugin-g", "plugin-b", "plugin-a", "tool-e", "tool-f"} {
+		_, err := fs.Stat("/plugins/" + name + "/" + name)
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestBulk_InstallGlob_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := newBulkFs(t)
+
+	results, err := NewBulk(fs, 1).InstallGlob(context.Background(), "/plugins", "/opt/plugin-a", "/opt/empty", "/opt/readme.md")
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "/opt/empty", results[0].Source)
+	assert.Nil(t, results[0].Plugin)
+	assert.Error(t, results[0].Err)
+
+	assert.Equal(t, "/opt/plugin-a", results[1].Source)
+	assert.Equal(t, "plugin-a", results[1].Plugin.Name)
+	assert.NoError(t, results[1].Err)
+
+	assert.Equal(t, "/opt/readme.md", results[2].Source)
+	assert.ErrorIs(t, results[2].Err, installer.ErrNoInstaller)
+
+	var bulkErr *BulkError
+
+	require.True(t, errors.As(err, &bulkErr))
+	assert.Len(t, bulkErr.Failures, 2)
+	assert.ErrorIs(t, err, installer.ErrNoInstaller)
+
+	var detectErr *DetectionError
+
+	require.True(t, errors.As(err, &detectErr))
+	assert.Equal(t, "/opt/empty", detectErr.Path)
+	assert.Contains(t, err.Error(), "could not install 2 plugin(s): /opt/empty: ")
+}
+
+func TestBulk_Install_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := NewBulk(newBulkFs(t), 0).Install(ctx, "/plugins", []string{"/opt/plugin-a"})
+
+	require.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,254 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/nhatthm/plugin-registry/installer"
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// CatalogIndexFile is the index file of a catalog.
+const CatalogIndexFile = "index.yaml"
+
+// Catalog is a plugin catalog on a file system, for example a shared NFS mount. The catalog is a directory tree of
+// <name>/<version>/ where each version directory is a source that the fs, zip or gzip installer supports:
+//
+//	./catalog/
+//	├── index.yaml
+//	└── my-plugin/
+//	    ├── 1.0.0/
+//	    │   ├── .plugin.registry.yaml
+//	    │   └── my-plugin-1.0.0-linux-amd64.tar.gz
+//	    └── 1.1.0/
+//	        ├── .plugin.registry.yaml
+//	        └── my-plugin/
+//
+// The index lists the versions of every plugin. Without the index, the directory tree is scanned.
+type Catalog struct {
+	fs   afero.Fs
+	root string
+	opts []Option
+}
+
+// CatalogEntry is a version of a plugin in a catalog.
+type CatalogEntry struct {
+	Name    string
+	Version *semver.Version
+	// Path is the directory of the version in the catalog.
+	Path string
+}
+
+// catalogIndex is the content of the catalog index file, for example:
+//
+//	plugins:
+//	  my-plugin:
+//	    - 1.0.0
+//	    - 1.1.0
+type catalogIndex struct {
+	Plugins map[string][]string `yaml:"plugins"`
+}
+
+// NewCatalog creates a new catalog reader at the root directory. The options are passed to the installers when
+// installing from the catalog.
+func NewCatalog(fs afero.Fs, root string, opts ...Option) *Catalog {
+	return &Catalog{
+		fs:   fs,
+		root: filepath.Clean(root),
+		opts: opts,
+	}
+}
+
+// Plugins lists the names of the plugins in the catalog.
+func (c *Catalog) Plugins() ([]string, error) {
+	idx, err := c.index()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(idx.Plugins))
+
+	for name := range idx.Plugins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Versions lists the versions of a plugin in the catalog, from the lowest to the highest.
+func (c *Catalog) Versions(name string) ([]CatalogEntry, error) {
+	idx, err := c.index()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, ok := idx.Plugins[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, plugin.ErrPluginNotExist)
+	}
+
+	entries := make([]CatalogEntry, 0, len(versions))
+
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s@%s: %w", name, v, err)
+		}
+
+		entries = append(entries, CatalogEntry{
+			Name:    name,
+			Version: sv,
+			Path:    filepath.Join(c.root, name, v),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Version.LessThan(entries[j].Version)
+	})
+
+	return entries, nil
+}
+
+// Resolve finds the highest version of a plugin that satisfies the semver constraint, for example "^1.2". The
+// constraint "latest" or an empty constraint resolves the latest stable version.
+func (c *Catalog) Resolve(name, constraint string) (CatalogEntry, error) {
+	entries, err := c.Versions(name)
+	if err != nil {
+		return CatalogEntry{}, err
+	}
+
+	versions := make([]*semver.Version, 0, len(entries))
+
+	for _, e := range entries {
+		versions = append(versions, e.Version)
+	}
+
+	found, err := resolveVersion(versions, constraint)
+	if err != nil {
+		return CatalogEntry{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return entries[found], nil
+}
+
+// Install resolves a version of the plugin and installs it with the fs, zip or gzip installer.
+func (c *Catalog) Install(ctx context.Context, dest, name, constraint string) (*plugin.Plugin, error) {
+	e, err := c.Resolve(name, constraint)
+	if err != nil {
+		return nil, installError(ctx, PhaseDetect, c.root, err, "could not resolve plugin version", "name", name, "constraint", constraint)
+	}
+
+	i, path, err := c.installer(e)
+	if err != nil {
+		return nil, installError(ctx, PhaseDetect, e.Path, err, "could not find plugin artifact", "path", e.Path)
+	}
+
+	return i.Install(ctx, dest, path)
+}
+
+// WriteIndex scans the catalog and writes its index file.
+func (c *Catalog) WriteIndex() error {
+	idx, err := c.scan()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(c.fs, filepath.Join(c.root, CatalogIndexFile), data, 0o644)
+}
+
+// installer returns the installer of the catalog entry, which installs the plugin with the version of the entry.
+func (c *Catalog) installer(e CatalogEntry) (installer.Installer, string, error) { //nolint: ireturn
+	opts := append([]Option{withVersion(e.Version.Original())}, c.opts...)
+
+	if _, _, err := parseFsPlugin(c.fs, e.Path); err == nil {
+		return NewFsInstaller(c.fs, opts...), e.Path, nil
+	}
+
+	p, err := plugin.Load(c.fs, e.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	p.Version = e.Version.Original()
+
+	path := filepath.Join(e.Path, p.ResolveArtifact(p.RuntimeArtifact()).File)
+
+	i, err := newArchiveInstallerFor(c.fs, path, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return i, path, nil
+}
+
+func (c *Catalog) index() (*catalogIndex, error) {
+	data, err := afero.ReadFile(c.fs, filepath.Join(c.root, CatalogIndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c.scan()
+		}
+
+		return nil, err
+	}
+
+	var idx catalogIndex
+
+	if err := yaml.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+
+	if idx.Plugins == nil {
+		idx.Plugins = map[string][]string{}
+	}
+
+	return &idx, nil
+}
+
+// scan builds the index from the directory tree. The directories that are not semver versions are ignored.
+func (c *Catalog) scan() (*catalogIndex, error) {
+	names, err := afero.ReadDir(c.fs, c.root)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &catalogIndex{Plugins: map[string][]string{}}
+
+	for _, n := range names {
+		if !n.IsDir() {
+			continue
+		}
+
+		versions, err := afero.ReadDir(c.fs, filepath.Join(c.root, n.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range versions {
+			if !v.IsDir() {
+				continue
+			}
+
+			if _, err := semver.NewVersion(v.Name()); err != nil {
+				continue
+			}
+
+			idx.Plugins[n.Name()] = append(idx.Plugins[n.Name()], v.Name())
+		}
+	}
+
+	return idx, nil
+}
@@ -0,0 +1,192 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCatalogFs(t *testing.T) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	artifact := fmt.Sprintf("my-plugin-1.1.0-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+
+	for path, content := range map[string][]byte{
+		"/catalog/my-plugin/1.0.0/.plugin.registry.yaml":        []byte("name: my-plugin"),
+		"/catalog/my-plugin/1.0.0/my-plugin/my-plugin":          []byte("#!/bin/bash\n# 1.0.0\n"),
+		"/catalog/my-plugin/1.1.0/.plugin.registry.yaml":        []byte("name: my-plugin"),
+		"/catalog/my-plugin/1.1.0/" + artifact:                  newTarGz(t, archiveEntry{name: "my-plugin/my-plugin", content: "#!/bin/bash\n# 1.1.0\n"}),
+		"/catalog/my-plugin/2.0.0-beta/.plugin.registry.yaml":   []byte("name: my-plugin"),
+		"/catalog/my-plugin/2.0.0-beta/my-plugin/my-plugin":     []byte("#!/bin/bash\n# 2.0.0-beta\n"),
+		"/catalog/my-plugin/docs/README.md":                     []byte("# my-plugin\n"),
+		"/catalog/my-other-plugin/0.1.0/.plugin.registry.yaml":  []byte("name: my-other-plugin"),
+		"/catalog/my-other-plugin/0.1.0/my-other-plugin.tar.xz": []byte("not supported"),
+		"/catalog/README.md":                                    []byte("# catalog\n"),
+	} {
+		require.NoError(t, afero.WriteFile(fs, path, content, 0o755))
+	}
+
+	return fs
+}
+
+func TestCatalog_Plugins(t *testing.T) {
+	t.Parallel()
+
+	c := NewCatalog(newCatalogFs(t), "/catalog")
+
+	actual, err := c.Plugins()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"my-other-plugin", "my-plugin"}, actual)
+}
+
+func TestCatalog_Versions(t *testing.T) {
+	t.Parallel()
+
+	c := NewCatalog(newCatalogFs(t), "/catalog")
+
+	entries, err := c.Versions("my-plugin")
+	require.NoError(t, err)
+
+	actual := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		actual = append(actual, e.Path)
+	}
+
+	expected := []string{
+		"/catalog/my-plugin/1.0.0",
+		"/catalog/my-plugin/1.1.0",
+		"/catalog/my-plugin/2.0.0-beta",
+	}
+
+	assert.Equal(t, expected, actual)
+
+	_, err = c.Versions("unknown")
+	require.EqualError(t, err, "unknown: plugin does not exist")
+}
+
+func TestCatalog_WriteIndex(t *testing.T) {
+	t.Parallel()
+
+	fs := newCatalogFs(t)
+	c := NewCatalog(fs, "/catalog")
+
+	require.NoError(t, c.WriteIndex())
+
+	data, err := afero.ReadFile(fs, "/catalog/index.yaml")
+	require.NoError(t, err)
+
+	expected := `plugins:
+    my-other-plugin:
+        - 0.1.0
+    my-plugin:
+        - 1.0.0
+        - 1.1.0
+        - 2.0.0-beta
+`
+
+	assert.Equal(t, expected, string(data))
+
+	// The index is used instead of scanning the catalog.
+	require.NoError(t, afero.WriteFile(fs, "/catalog/index.yaml", []byte("plugins:\n  my-plugin: [1.0.0]\n"), 0o644))
+
+	actual, err := c.Plugins()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"my-plugin"}, actual)
+}
+
+func TestCatalog_Install(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario        string
+		name            string
+		constraint      string
+		expectedVersion string
+		expectedContent string
+		expectedError   string
+	}{
+		{
+			scenario:        "latest from gzip",
+			name:            "my-plugin",
+			constraint:      "latest",
+			expectedVersion: "1.1.0",
+			expectedContent: "#!/bin/bash\n# 1.1.0\n",
+		},
+		{
+			scenario:        "constraint from folder",
+			name:            "my-plugin",
+			constraint:      ">=1.0 <1.1",
+			expectedVersion: "1.0.0",
+			expectedContent: "#!/bin/bash\n# 1.0.0\n",
+		},
+		{
+			scenario:        "prerelease",
+			name:            "my-plugin",
+			constraint:      "2.0.0-beta",
+			expectedVersion: "2.0.0-beta",
+			expectedContent: "#!/bin/bash\n# 2.0.0-beta\n",
+		},
+		{
+			scenario:      "no matching version",
+			name:          "my-plugin",
+			constraint:    "^3",
+			expectedError: "could not resolve plugin version: my-plugin: ^3: no matching version",
+		},
+		{
+			scenario:      "unknown plugin",
+			name:          "unknown",
+			expectedError: "could not resolve plugin version: unknown: plugin does not exist",
+		},
+		{
+			scenario: "no artifact",
+			name:     "my-other-plugin",
+			expectedError: fmt.Sprintf(
+				"could not find plugin artifact: /catalog/my-other-plugin/0.1.0/my-other-plugin-0.1.0-%s-%s.tar.gz: no installable artifact",
+				runtime.GOOS, runtime.GOARCH,
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			srcFs := newCatalogFs(t)
+			destFs := afero.NewMemMapFs()
+
+			c := NewCatalog(srcFs, "/catalog", WithDestinationFs(destFs))
+
+			p, err := c.Install(context.Background(), "/app/plugins", tc.name, tc.constraint)
+
+			if tc.expectedError != "" {
+				assert.Nil(t, p)
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedVersion, p.Version)
+
+			data, err := afero.ReadFile(destFs, "/app/plugins/my-plugin/my-plugin")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedContent, string(data))
+
+			r, err := readRecord(destFs, "/app/plugins", "my-plugin")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedVersion, r.Version)
+		})
+	}
+}
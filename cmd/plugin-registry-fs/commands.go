@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nhatthm/plugin-registry/installer"
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+
+	fs "github.com/nhatthm/plugin-registry-fs"
+)
+
+// pluginResult is the result of a command for a plugin.
+type pluginResult struct {
+	Name      string      `json:"name,omitempty"`
+	Version   string      `json:"version,omitempty"`
+	Source    string      `json:"source,omitempty"`
+	Path      string      `json:"path,omitempty"`
+	Installer string      `json:"installer,omitempty"`
+	Action    string      `json:"action,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	Installed *time.Time  `json:"installed_at,omitempty"`
+	Diagnoses []diagnosis `json:"diagnoses,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// diagnosis is the JSON output of a fs.Diagnosis.
+type diagnosis struct {
+	Installer string `json:"installer"`
+	Supported bool   `json:"supported"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// pluginsResult is the result of a command for several plugins.
+type pluginsResult struct {
+	Plugins []pluginResult `json:"plugins"`
+}
+
+func (r *pluginsResult) text(w io.Writer) {
+	for _, p := range r.Plugins {
+		fields := []string{p.Name}
+
+		for _, f := range []string{p.Version, p.Action, p.Status, p.Installer, p.Source, p.Path} {
+			if f != "" {
+				fields = append(fields, f)
+			}
+		}
+
+		if p.Error != "" {
+			fields = append(fields, "error: "+p.Error)
+		}
+
+		_, _ = fmt.Fprintln(w, strings.Join(fields, "\t")) //nolint: errcheck
+	}
+}
+
+// add adds the result of a plugin, and keeps the first error.
+func (r *pluginsResult) add(p pluginResult, err, first error) error {
+	if err != nil {
+		p.Error = err.Error()
+	}
+
+	r.Plugins = append(r.Plugins, p)
+
+	if first != nil {
+		return first
+	}
+
+	return err
+}
+
+func runInstall(ctx context.Context, e *env, args []string) (result, error) {
+	dest := e.flags.String("dest", "", "the destination directory (required)")
+
+	if err := e.parse(args, 1, "SOURCE..."); err != nil {
+		return nil, err
+	}
+
+	if err := requireDest(*dest); err != nil {
+		return nil, err
+	}
+
+	res := &pluginsResult{Plugins: []pluginResult{}}
+
+	var first error
+
+	for _, src := range e.flags.Args() {
+		r := pluginResult{Source: src}
+
+		i, err := installer.Find(ctx, src)
+		if err != nil {
+			err = &fs.DetectionError{Path: src, Diagnoses: fs.Diagnose(e.fs, src)}
+		} else {
+			var p *plugin.Plugin
+
+			if p, err = i.Install(ctx, *dest, src); err == nil {
+				r.Name, r.Version, r.Path, r.Action = p.Name, p.Version, filepath.Join(*dest, p.Name), "installed"
+			}
+		}
+
+		first = res.add(r, err, first)
+	}
+
+	return res, first
+}
+
+func runPlan(_ context.Context, e *env, args []string) (result, error) {
+	dest := e.flags.String("dest", "", "the destination directory (required)")
+
+	if err := e.parse(args, 1, "SOURCE..."); err != nil {
+		return nil, err
+	}
+
+	if err := requireDest(*dest); err != nil {
+		return nil, err
+	}
+
+	res := &pluginsResult{Plugins: []pluginResult{}}
+
+	var first error
+
+	for _, src := range e.flags.Args() {
+		r, err := plan(e.fs, *dest, src)
+
+		first = res.add(r, err, first)
+	}
+
+	return res, first
+}
+
+// plan tells which installer supports the source, and what it would install.
+func plan(afs afero.Fs, dest, src string) (pluginResult, error) {
+	r := pluginResult{Source: src}
+	diagnoses := fs.Diagnose(afs, src)
+
+	for _, d := range diagnoses {
+		dd := diagnosis{Installer: d.Installer, Supported: d.Supported()}
+
+		if d.Err != nil {
+			dd.Reason = d.Err.Error()
+		}
+
+		r.Diagnoses = append(r.Diagnoses, dd)
+
+		if d.Supported() && r.Installer == "" {
+			r.Installer = d.Installer
+		}
+	}
+
+	if r.Installer == "" {
+		return r, &fs.DetectionError{Path: src, Diagnoses: diagnoses}
+	}
+
+	metadataDir := src
+	if r.Installer != "fs" {
+		metadataDir = filepath.Dir(src)
+	}
+
+	p, err := plugin.Load(afs, metadataDir)
+	if err != nil {
+		return r, &fs.InstallError{Phase: fs.PhaseParse, Source: src, Err: err}
+	}
+
+	r.Name, r.Version, r.Path, r.Action = p.Name, p.Version, filepath.Join(dest, p.Name), "install"
+
+	if _, err := afs.Stat(r.Path); err == nil {
+		r.Action = "replace"
+	}
+
+	return r, nil
+}
+
+func runVerify(_ context.Context, e *env, args []string) (result, error) {
+	dest := e.flags.String("dest", "", "the destination directory (required)")
+	platform := e.flags.String("platform", "", "check that the plugins are built for the platform, in the form os/arch")
+
+	if err := e.parse(args, 1, "NAME..."); err != nil {
+		return nil, err
+	}
+
+	if err := requireDest(*dest); err != nil {
+		return nil, err
+	}
+
+	var opts []fs.Option
+
+	if *platform != "" {
+		parts := strings.SplitN(*platform, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: invalid -platform %q", errUsage, *platform)
+		}
+
+		opts = append(opts, fs.WithPlatformCheck(plugin.NewArtifactIdentifier(parts[0], parts[1])))
+	}
+
+	i := fs.NewFsInstaller(e.fs, opts...)
+	res := &pluginsResult{Plugins: []pluginResult{}}
+
+	var first error
+
+	for _, name := range e.flags.Args() {
+		r := pluginResult{Name: name, Path: filepath.Join(*dest, name), Action: "valid"}
+
+		err := i.Verify(*dest, name)
+		if err != nil {
+			r.Action = "invalid"
+		}
+
+		first = res.add(r, err, first)
+	}
+
+	return res, first
+}
+
+func runUninstall(ctx context.Context, e *env, args []string) (result, error) {
+	dest := e.flags.String("dest", "", "the destination directory (required)")
+
+	if err := e.parse(args, 1, "NAME..."); err != nil {
+		return nil, err
+	}
+
+	if err := requireDest(*dest); err != nil {
+		return nil, err
+	}
+
+	i := fs.NewFsInstaller(e.fs)
+	res := &pluginsResult{Plugins: []pluginResult{}}
+
+	var first error
+
+	for _, name := range e.flags.Args() {
+		r := pluginResult{Name: name, Path: filepath.Join(*dest, name)}
+
+		err := i.Uninstall(ctx, *dest, name)
+		if err == nil {
+			r.Action = "uninstalled"
+		}
+
+		first = res.add(r, err, first)
+	}
+
+	return res, first
+}
+
+func runList(ctx context.Context, e *env, args []string) (result, error) {
+	dest := e.flags.String("dest", "", "the destination directory (required)")
+
+	if err := e.parse(args, 0, ""); err != nil {
+		return nil, err
+	}
+
+	if err := requireDest(*dest); err != nil {
+		return nil, err
+	}
+
+	plugins, err := fs.NewFsInstaller(e.fs).List(ctx, *dest)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pluginsResult{Plugins: []pluginResult{}}
+
+	for _, p := range plugins {
+		r := pluginResult{
+			Name:      p.Name,
+			Version:   p.Version,
+			Source:    p.Source,
+			Path:      p.Path,
+			Installer: p.Installer,
+			Status:    string(p.Status),
+		}
+
+		if !p.InstalledAt.IsZero() {
+			installedAt := p.InstalledAt
+			r.Installed = &installedAt
+		}
+
+		if p.Problem != nil {
+			r.Error = p.Problem.Error()
+		}
+
+		res.Plugins = append(res.Plugins, r)
+	}
+
+	return res, nil
+}
+
+// packResult is the result of the pack command.
+type packResult struct {
+	Name         string `json:"name"`
+	Version      string `json:"version,omitempty"`
+	Archive      string `json:"archive"`
+	Metadata     string `json:"metadata"`
+	Checksum     string `json:"checksum"`
+	ChecksumFile string `json:"checksum_file"`
+}
+
+func (r *packResult) text(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "%s\t%s\n", r.Archive, r.Checksum) //nolint: errcheck
+}
+
+func runPack(_ context.Context, e *env, args []string) (result, error) {
+	format := e.flags.String("format", string(fs.FormatTarGz), "the archive format, zip or tar.gz")
+	out := e.flags.String("out", "dist", "the output directory")
+	name := e.flags.String("name", "", "the file name of the archive, from the plugin metadata by default")
+
+	if err := e.parse(args, 1, "SOURCE"); err != nil {
+		return nil, err
+	}
+
+	var opts []fs.PackOption
+
+	if *name != "" {
+		opts = append(opts, fs.WithArchiveName(*name))
+	}
+
+	pkg, err := fs.Pack(e.fs, e.flags.Arg(0), *out, fs.Format(*format), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &packResult{
+		Name:         pkg.Plugin.Name,
+		Version:      pkg.Plugin.Version,
+		Archive:      pkg.Archive,
+		Metadata:     pkg.Metadata,
+		Checksum:     pkg.Checksum,
+		ChecksumFile: pkg.ChecksumFile,
+	}, nil
+}
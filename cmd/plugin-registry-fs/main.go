@@ -0,0 +1,241 @@
+// Command plugin-registry-fs installs, inspects and packs plugins with the file system installers.
+//
+// Usage:
+//
+//	plugin-registry-fs <command> [flags] [args]
+//
+// The commands are:
+//
+//	install    install plugins from folders or archives
+//	plan       show what install would do, without installing
+//	verify     check installed plugins
+//	uninstall  remove installed plugins
+//	list       list installed plugins
+//	pack       build an archive from a plugin folder
+//
+// Every command accepts -json to print its result as JSON. The exit code tells the kind of failure, see exitCode.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	fsCtx "github.com/nhatthm/plugin-registry/context"
+	"github.com/spf13/afero"
+
+	fs "github.com/nhatthm/plugin-registry-fs"
+)
+
+// The exit codes.
+const (
+	exitOK              = 0
+	exitFailure         = 1
+	exitUsage           = 2
+	exitNoInstaller     = 3
+	exitInvalidMetadata = 4
+	exitLocked          = 5
+	exitUnsafeArchive   = 6
+	exitInvalidPlugin   = 7
+	exitHookFailed      = 8
+	exitNotFound        = 9
+)
+
+// errUsage indicates that the command line is invalid.
+var errUsage = errors.New("invalid usage")
+
+const usage = `Usage: plugin-registry-fs <command> [flags] [args]
+
+Commands:
+  install    install plugins from folders or archives
+  plan       show what install would do, without installing
+  verify     check installed plugins
+  uninstall  remove installed plugins
+  list       list installed plugins
+  pack       build an archive from a plugin folder
+
+Run "plugin-registry-fs <command> -h" for the flags of a command.
+`
+
+// command runs a subcommand and returns its result, which is printed as JSON or as text.
+type command func(ctx context.Context, env *env, args []string) (result, error)
+
+// result is the result of a command.
+type result interface {
+	// text writes the result for humans.
+	text(w io.Writer)
+}
+
+// env is the environment of a command.
+type env struct {
+	fs     afero.Fs
+	flags  *flag.FlagSet
+	json   bool
+	stderr io.Writer
+}
+
+var commands = map[string]command{
+	"install":   runInstall,
+	"plan":      runPlan,
+	"verify":    runVerify,
+	"uninstall": runUninstall,
+	"list":      runList,
+	"pack":      runPack,
+}
+
+func main() {
+	ctx := fsCtx.WithFs(context.Background(), afero.NewOsFs())
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command line and returns the exit code. The file system is taken from the context.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, usage) //nolint: errcheck
+
+		return exitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage) //nolint: errcheck
+
+		return exitUsage
+	}
+
+	e := &env{
+		fs:     fsCtx.Fs(ctx),
+		flags:  flag.NewFlagSet(args[0], flag.ContinueOnError),
+		stderr: stderr,
+	}
+
+	e.flags.SetOutput(stderr)
+	e.flags.BoolVar(&e.json, "json", false, "print the result as JSON")
+
+	res, err := cmd(ctx, e, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	code := exitCode(err)
+
+	if e.json {
+		out := output{Result: res}
+
+		if err != nil {
+			out.Error = &errorOutput{Message: err.Error(), Kind: fs.ErrorKind(err), Code: code}
+		}
+
+		if err := json.NewEncoder(stdout).Encode(out); err != nil {
+			_, _ = fmt.Fprintln(stderr, "error:", err) //nolint: errcheck
+
+			return exitFailure
+		}
+	} else if res != nil {
+		res.text(stdout)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "error:", err) //nolint: errcheck
+	}
+
+	return code
+}
+
+// output is the JSON output of a command.
+type output struct {
+	Result result       `json:"result,omitempty"`
+	Error  *errorOutput `json:"error,omitempty"`
+}
+
+// errorOutput is the JSON output of an error.
+type errorOutput struct {
+	Message string `json:"message"`
+	Kind    string `json:"kind"`
+	Code    int    `json:"exit_code"`
+}
+
+// exitCode maps the error to an exit code:
+//
+//	0  success
+//	1  other failure
+//	2  invalid usage
+//	3  no installer supports the source
+//	4  invalid plugin metadata
+//	5  the plugin is locked by another process
+//	6  the archive has an unsafe entry
+//	7  the plugin is not valid
+//	8  a hook failed
+//	9  the plugin or the source does not exist
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+
+	switch fs.ErrorKind(err) {
+	case "no_installer", string(fs.PhaseDetect):
+		return exitNoInstaller
+
+	case string(fs.PhaseParse):
+		return exitInvalidMetadata
+
+	case "locked":
+		return exitLocked
+
+	case "unsafe_entry":
+		return exitUnsafeArchive
+
+	case "invalid_plugin", string(fs.PhaseVerify):
+		return exitInvalidPlugin
+
+	case string(fs.PhaseHook):
+		return exitHookFailed
+
+	case "not_found":
+		return exitNotFound
+	}
+
+	return exitFailure
+}
+
+// parse parses the flags and checks the number of arguments.
+func (e *env) parse(args []string, minArgs int, usage string) error {
+	e.flags.Usage = func() {
+		_, _ = fmt.Fprintf(e.stderr, "Usage: plugin-registry-fs %s [flags] %s\n\nFlags:\n", e.flags.Name(), usage) //nolint: errcheck
+		e.flags.PrintDefaults()
+	}
+
+	if err := e.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	if e.flags.NArg() < minArgs {
+		e.flags.Usage()
+
+		return fmt.Errorf("%w: missing %s", errUsage, usage)
+	}
+
+	return nil
+}
+
+// requireDest checks that the destination flag is set.
+func requireDest(dest string) error {
+	if dest == "" {
+		return fmt.Errorf("%w: missing -dest", errUsage)
+	}
+
+	return nil
+}
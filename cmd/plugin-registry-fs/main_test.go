@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	fsCtx "github.com/nhatthm/plugin-registry/context"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFs(t *testing.T) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"/src/.plugin.registry.yaml":        "name: my-plugin\nversion: 1.2.0\n",
+		"/src/my-plugin/my-plugin":          "#!/bin/bash\n",
+		"/bad/.plugin.registry.yaml":        "name: [\n",
+		"/bad/bad-plugin/bad-plugin":        "#!/bin/bash\n",
+		"/app/plugins/other/other":          "#!/bin/bash\n",
+		"/app/plugins/.my-plugin.lock.yaml": "",
+	}
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o755))
+	}
+
+	return fs
+}
+
+func runTest(t *testing.T, fs afero.Fs, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+
+	code := run(fsCtx.WithFs(context.Background(), fs), args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_ExitCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario       string
+		args           []string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{
+			scenario:     "no command",
+			expectedCode: exitUsage,
+		},
+		{
+			scenario:     "unknown command",
+			args:         []string{"unknown"},
+			expectedCode: exitUsage,
+		},
+		{
+			scenario:       "missing dest",
+			args:           []string{"install", "/src"},
+			expectedCode:   exitUsage,
+			expectedStderr: "error: invalid usage: missing -dest\n",
+		},
+		{
+			scenario:     "missing source",
+			args:         []string{"install", "-dest", "/app/plugins"},
+			expectedCode: exitUsage,
+		},
+		{
+			scenario:     "help",
+			args:         []string{"install", "-h"},
+			expectedCode: exitOK,
+		},
+		{
+			scenario:       "install",
+			args:           []string{"install", "-dest", "/app/plugins", "/src"},
+			expectedCode:   exitOK,
+			expectedStdout: "my-plugin\t1.2.0\tinstalled\t/src\t/app/plugins/my-plugin\n",
+		},
+		{
+			scenario:     "install no installer",
+			args:         []string{"install", "-dest", "/app/plugins", "/unknown"},
+			expectedCode: exitNoInstaller,
+		},
+		{
+			scenario:       "plan",
+			args:           []string{"plan", "-dest", "/app/plugins", "/src"},
+			expectedCode:   exitOK,
+			expectedStdout: "my-plugin\t1.2.0\tinstall\tfs\t/src\t/app/plugins/my-plugin\n",
+		},
+		{
+			scenario:     "plan unreadable metadata",
+			args:         []string{"plan", "-dest", "/app/plugins", "/bad"},
+			expectedCode: exitNoInstaller,
+		},
+		{
+			scenario:       "verify not found",
+			args:           []string{"verify", "-dest", "/app/plugins", "my-plugin"},
+			expectedCode:   exitNotFound,
+			expectedStdout: "my-plugin\tinvalid\t/app/plugins/my-plugin\terror: my-plugin: plugin is not installed\n",
+			expectedStderr: "error: my-plugin: plugin is not installed\n",
+		},
+		{
+			scenario:     "verify invalid platform",
+			args:         []string{"verify", "-dest", "/app/plugins", "-platform", "linux", "other"},
+			expectedCode: exitUsage,
+		},
+		{
+			scenario:       "list",
+			args:           []string{"list", "-dest", "/app/plugins"},
+			expectedCode:   exitOK,
+			expectedStdout: "other\tforeign\t/app/plugins/other\terror: other: plugin has no install record\n",
+		},
+		{
+			scenario:       "uninstall",
+			args:           []string{"uninstall", "-dest", "/app/plugins", "other"},
+			expectedCode:   exitOK,
+			expectedStdout: "other\tuninstalled\t/app/plugins/other\n",
+		},
+		{
+			scenario:     "uninstall not found",
+			args:         []string{"uninstall", "-dest", "/app/plugins", "my-plugin"},
+			expectedCode: exitNotFound,
+		},
+		{
+			scenario:     "pack",
+			args:         []string{"pack", "-format", "zip", "-name", "my-plugin.zip", "-out", "/dist", "/src"},
+			expectedCode: exitOK,
+		},
+		{
+			scenario:     "pack unsupported format",
+			args:         []string{"pack", "-format", "rar", "/src"},
+			expectedCode: exitFailure,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			code, stdout, stderr := runTest(t, newTestFs(t), tc.args...)
+
+			assert.Equal(t, tc.expectedCode, code, stderr)
+
+			if tc.expectedStdout != "" {
+				assert.Equal(t, tc.expectedStdout, stdout)
+			}
+
+			if tc.expectedStderr != "" {
+				assert.Equal(t, tc.expectedStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	t.Parallel()
+
+	fs := newTestFs(t)
+
+	code, stdout, _ := runTest(t, fs, "install", "-json", "-dest", "/app/plugins", "/src", "/unknown")
+
+	assert.Equal(t, exitNoInstaller, code)
+
+	var out struct {
+		Result pluginsResult `json:"result"`
+		Error  errorOutput   `json:"error"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+
+	require.Len(t, out.Result.Plugins, 2)
+	assert.Equal(t, pluginResult{
+		Name:    "my-plugin",
+		Version: "1.2.0",
+		Source:  "/src",
+		Path:    "/app/plugins/my-plugin",
+		Action:  "installed",
+	}, out.Result.Plugins[0])
+	assert.Equal(t, "/unknown", out.Result.Plugins[1].Source)
+	assert.NotEmpty(t, out.Result.Plugins[1].Error)
+	assert.Equal(t, errorOutput{Message: out.Result.Plugins[1].Error, Kind: "no_installer", Code: exitNoInstaller}, out.Error)
+
+	code, stdout, _ = runTest(t, fs, "list", "-json", "-dest", "/app/plugins")
+
+	assert.Equal(t, exitOK, code)
+
+	out.Result.Plugins = nil
+
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	require.Len(t, out.Result.Plugins, 2)
+
+	installed := out.Result.Plugins[0]
+
+	require.NotNil(t, installed.Installed)
+	installed.Installed = nil
+
+	assert.Equal(t, pluginResult{
+		Name:      "my-plugin",
+		Version:   "1.2.0",
+		Source:    "/src",
+		Path:      "/app/plugins/my-plugin",
+		Installer: "fs",
+		Status:    "installed",
+	}, installed)
+	assert.Equal(t, pluginResult{
+		Name:   "other",
+		Path:   "/app/plugins/other",
+		Status: "foreign",
+		Error:  "other: plugin has no install record",
+	}, out.Result.Plugins[1])
+
+	code, stdout, _ = runTest(t, fs, "verify", "-json", "-dest", "/app/plugins", "my-plugin")
+
+	assert.Equal(t, exitOK, code)
+	assert.JSONEq(t, `{"result":{"plugins":[
+		{"name":"my-plugin","path":"/app/plugins/my-plugin","action":"valid"}
+	]}}`, stdout)
+}
+
+func TestRun_List_InstalledAt(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"/app/plugins/.a.install.yaml": "installer: fs\nsource: /src/a\ninstalled_at: 2020-01-01T00:00:00Z\n",
+		"/app/plugins/a/a":             "#!/bin/bash\n",
+		"/app/plugins/.b.install.yaml": "installer: fs\nsource: /src/b\ninstalled_at: 2024-06-06T00:00:00Z\n",
+		"/app/plugins/b/b":             "#!/bin/bash\n",
+	}
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o755))
+	}
+
+	code, stdout, _ := runTest(t, fs, "list", "-json", "-dest", "/app/plugins")
+
+	assert.Equal(t, exitOK, code)
+
+	var out struct {
+		Result pluginsResult `json:"result"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	require.Len(t, out.Result.Plugins, 2)
+
+	for i, expected := range []time.Time{
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 6, 0, 0, 0, 0, time.UTC),
+	} {
+		require.NotNil(t, out.Result.Plugins[i].Installed)
+		assert.True(t, expected.Equal(*out.Result.Plugins[i].Installed), out.Result.Plugins[i].Name)
+	}
+}
@@ -0,0 +1,155 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"go.nhat.io/aferocopy/v2"
+)
+
+// WithDedup makes the filesystem installer clone the plugin files using reflinks where the file system supports them
+// and hardlinks otherwise, falling back to copying when neither is possible. It only takes effect when both the source
+// and the destination are OS file systems.
+//
+// Hardlinked files share their content and permissions with the source, changing one changes the other.
+func WithDedup() Option {
+	return func(c *config) {
+		c.dedup = true
+	}
+}
+
+func canDedup(cfg *config) bool {
+	if !cfg.dedup {
+		return false
+	}
+
+	_, srcOk := cfg.srcFs.(*afero.OsFs)
+	_, destOk := cfg.destFs.(*afero.OsFs)
+
+	return srcOk && destOk
+}
+
+// dedupCopy clones the source to the destination, the source can be either a file or a directory.
+func dedupCopy(ctx context.Context, cfg *config, src, dest string) error {
+	return afero.Walk(cfg.srcFs, src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if cfg.filter.skip(rel, fi.IsDir()) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		target := filepath.Join(dest, rel)
+
+		switch {
+		case fi.IsDir():
+			return cfg.destFs.MkdirAll(target, fi.Mode().Perm())
+
+		case fi.Mode().IsRegular():
+			return dedupFile(ctx, cfg, path, target, fi)
+		}
+
+		// Symlinks and special files are left to aferocopy.
+		return copyPath(ctx, cfg, path, target)
+	})
+}
+
+func dedupFile(ctx context.Context, cfg *config, src, dest string, fi os.FileInfo) error {
+	if err := cfg.destFs.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	if err := reflink(src, dest, fi); err == nil {
+		cfg.log(ctx).Debug(ctx, "cloned file", "src", src, "path", dest)
+		cfg.observer.ObserveEntry(ctx, src, 0, fi.Size())
+
+		return nil
+	}
+
+	if err := os.Link(src, dest); err == nil {
+		cfg.log(ctx).Debug(ctx, "linked file", "src", src, "path", dest)
+		cfg.observer.ObserveEntry(ctx, src, 0, fi.Size())
+
+		return nil
+	}
+
+	return copyPath(ctx, cfg, src, dest)
+}
+
+func copyPath(ctx context.Context, cfg *config, src, dest string) error {
+	err := aferocopy.Copy(src, dest, aferocopy.Options{
+		SrcFs:  cfg.srcFs,
+		DestFs: cfg.destFs,
+		Skip: func(srcFs afero.Fs, path string) (bool, error) {
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return false, err
+			}
+
+			fi, err := srcFs.Stat(path)
+			if err != nil {
+				return false, err
+			}
+
+			if cfg.filter.skip(rel, fi.IsDir()) {
+				return true, nil
+			}
+
+			if fi.Mode().IsRegular() {
+				cfg.log(ctx).Debug(ctx, "copying file", "src", path, "path", filepath.Join(dest, rel))
+				cfg.observer.ObserveEntry(ctx, path, fi.Size(), fi.Size())
+			}
+
+			return false, nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return copyTimes(cfg, src, dest)
+}
+
+// copyTimes sets the modification time of the copied files and folders to the one of their source. aferocopy can only
+// preserve the times that it reads from the system stat, which the in-memory file systems do not have.
+func copyTimes(cfg *config, src, dest string) error {
+	return afero.Walk(cfg.srcFs, src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() && !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		err = cfg.destFs.Chtimes(filepath.Join(dest, rel), fi.ModTime(), fi.ModTime())
+
+		switch {
+		// The filtered out entries are not copied.
+		case os.IsNotExist(err) && fi.IsDir():
+			return filepath.SkipDir
+
+		case os.IsNotExist(err):
+			return nil
+		}
+
+		return err
+	})
+}
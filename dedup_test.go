@@ -0,0 +1,141 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/aferocopy/v2"
+)
+
+func TestCanDedup(t *testing.T) {
+	t.Parallel()
+
+	osFs := afero.NewOsFs()
+	memFs := afero.NewMemMapFs()
+
+	testCases := []struct {
+		scenario string
+		config   config
+		expected bool
+	}{
+		{
+			scenario: "disabled",
+			config:   newConfig(osFs),
+		},
+		{
+			scenario: "source is not os fs",
+			config:   newConfig(osFs, WithDedup(), WithSourceFs(memFs)),
+		},
+		{
+			scenario: "destination is not os fs",
+			config:   newConfig(osFs, WithDedup(), WithDestinationFs(memFs)),
+		},
+		{
+			scenario: "enabled",
+			config:   newConfig(osFs, WithDedup()),
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, canDedup(&tc.config))
+		})
+	}
+}
+
+func TestFsInstaller_Install_Dedup(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		fixture  string
+	}{
+		{
+			scenario: "folder",
+			fixture:  "resources/fixtures/fs/folder",
+		},
+		{
+			scenario: "file",
+			fixture:  "resources/fixtures/fs/file",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Source and destination must be on the same file system for hardlinks.
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src")
+			dest := filepath.Join(dir, "dest")
+
+			require.NoError(t, aferocopy.Copy(tc.fixture, src))
+
+			osFs := afero.NewOsFs()
+			i := NewFsInstaller(osFs, WithDedup())
+
+			_, err := i.Install(context.Background(), dest, src)
+			require.NoError(t, err)
+
+			srcFile := filepath.Join(src, "my-plugin")
+			if isDir, _ := afero.IsDir(osFs, srcFile); isDir { //nolint: errcheck
+				srcFile = filepath.Join(srcFile, "my-plugin")
+			}
+
+			destFile := filepath.Join(dest, "my-plugin", "my-plugin")
+
+			srcInfo, err := os.Stat(srcFile)
+			require.NoError(t, err)
+
+			destInfo, err := os.Stat(destFile)
+			require.NoError(t, err)
+
+			assert.Equal(t, srcInfo.Mode(), destInfo.Mode())
+
+			data, err := afero.ReadFile(osFs, destFile)
+			require.NoError(t, err)
+
+			assert.Equal(t, "#!/bin/bash\n", string(data))
+		})
+	}
+}
+
+func TestDedupFile_Hardlink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest", "file")
+
+	require.NoError(t, os.WriteFile(src, []byte("hello world"), 0o644)) //nolint: gosec
+
+	fi, err := os.Stat(src)
+	require.NoError(t, err)
+
+	cfg := newConfig(afero.NewOsFs(), WithDedup())
+
+	require.NoError(t, dedupFile(context.Background(), &cfg, src, dest, fi))
+
+	destInfo, err := os.Stat(dest)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(dest) //nolint: gosec
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello world", string(data))
+
+	// Without reflink support, the file must be hardlinked.
+	if err := reflink(src, filepath.Join(dir, "probe"), fi); err != nil {
+		assert.True(t, os.SameFile(fi, destInfo))
+	}
+}
@@ -0,0 +1,301 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/nhatthm/plugin-registry/plugin"
+)
+
+var (
+	// ErrDependencyConflict indicates that no version of a plugin satisfies all the constraints on it.
+	ErrDependencyConflict = errors.New("dependency conflict")
+	// ErrDependencyCycle indicates that the plugins depend on each other.
+	ErrDependencyCycle = errors.New("dependency cycle")
+)
+
+// requirement is a version constraint on a plugin.
+type requirement struct {
+	constraint string
+	// requiredBy is the name of the plugin that declares the dependency, empty for the requested plugins.
+	requiredBy string
+}
+
+func (r requirement) String() string {
+	constraint := r.constraint
+	if constraint == "" {
+		constraint = "*"
+	}
+
+	if r.requiredBy == "" {
+		return constraint
+	}
+
+	return fmt.Sprintf("%s (required by %s)", constraint, r.requiredBy)
+}
+
+// InstallWithDependencies installs the plugins with the given names, and the plugins they depend on, from the source
+// directories. Each directory is read like Installer.Plugins does. The dependencies are declared in
+// .plugin.registry.yaml with semver constraints:
+//
+//	name: my-plugin
+//	version: 1.2.0
+//	dependencies:
+//	  my-other-plugin: ^1.0
+//
+// When several sources have the same plugin, the highest version that satisfies all the constraints is installed. The
+// plugins are installed in the topological order, the dependencies first.
+func (i *Installer) InstallWithDependencies(ctx context.Context, dest string, dirs []string, names ...string) ([]*plugin.Plugin, error) {
+	candidates := make(map[string][]Source)
+
+	for _, dir := range dirs {
+		sources, err := findSources(i.srcFs, dir)
+		if err != nil {
+			err = installError(ctx, PhaseDetect, dir, err, "could not parse plugin path", "path", dir)
+
+			return nil, i.observeDetectError(ctx, err)
+		}
+
+		for _, s := range sources {
+			candidates[s.Plugin.Name] = append(candidates[s.Plugin.Name], s)
+		}
+	}
+
+	ordered, err := resolveDependencies(candidates, names)
+	if err != nil {
+		err = installError(ctx, PhaseDetect, "", err, "could not resolve dependencies", "plugins", names)
+
+		return nil, i.observeDetectError(ctx, err)
+	}
+
+	return i.installSources(ctx, dest, ordered)
+}
+
+// resolveDependencies selects a source for the requested plugins and their dependencies, and returns them in the
+// topological order. The requirements are collected from the current selections only, and the selections are
+// recomputed until they do not change, so that the dependencies of a version that is not selected anymore are dropped
+// and the result does not depend on the order of the names.
+func resolveDependencies(candidates map[string][]Source, names []string) ([]Source, error) {
+	selected := make(map[string]Source)
+	seen := make(map[string]struct{})
+
+	for {
+		order, requirements := collectRequirements(names, selected)
+		next := make(map[string]Source, len(order))
+
+		var firstErr error
+
+		for _, name := range order {
+			s, err := selectSource(name, candidates[name], requirements[name])
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				continue
+			}
+
+			next[name] = s
+		}
+
+		key := selectionKey(next)
+
+		if key == selectionKey(selected) {
+			if firstErr != nil {
+				return nil, firstErr
+			}
+
+			return sortTopologically(selected)
+		}
+
+		// The selections go back and forth between the same versions.
+		if _, ok := seen[key]; ok {
+			if firstErr != nil {
+				return nil, firstErr
+			}
+
+			return nil, fmt.Errorf("%s: %w", strings.Join(names, ", "), ErrDependencyConflict)
+		}
+
+		seen[key] = struct{}{}
+		selected = next
+	}
+}
+
+// collectRequirements collects the requirements on the requested plugins and on the dependencies of the selected
+// plugins, in the breadth-first order from the requested plugins.
+func collectRequirements(names []string, selected map[string]Source) ([]string, map[string][]requirement) {
+	requirements := make(map[string][]requirement)
+	order := make([]string, 0, len(names))
+	queue := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if _, ok := requirements[name]; !ok {
+			order = append(order, name)
+			queue = append(queue, name)
+		}
+
+		requirements[name] = append(requirements[name], requirement{})
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		s, ok := selected[name]
+		if !ok {
+			continue
+		}
+
+		for _, dep := range dependencies(s) {
+			if _, ok := requirements[dep]; !ok {
+				order = append(order, dep)
+				queue = append(queue, dep)
+			}
+
+			requirements[dep] = append(requirements[dep], requirement{
+				constraint: s.metadata.Dependencies[dep],
+				requiredBy: name,
+			})
+		}
+	}
+
+	return order, requirements
+}
+
+// selectionKey identifies the selected sources.
+func selectionKey(selected map[string]Source) string {
+	keys := make([]string, 0, len(selected))
+
+	for name, s := range selected {
+		keys = append(keys, name+"="+s.Path)
+	}
+
+	sort.Strings(keys)
+
+	return strings.Join(keys, "\n")
+}
+
+// selectSource selects the highest version of the plugin that satisfies all the requirements.
+func selectSource(name string, candidates []Source, reqs []requirement) (Source, error) {
+	if len(candidates) == 0 {
+		return Source{}, fmt.Errorf("%s %s: %w", name, reqs[len(reqs)-1], plugin.ErrPluginNotExist)
+	}
+
+	found := -1
+
+	for i, s := range candidates {
+		ok, err := satisfies(s, reqs)
+		if err != nil {
+			return Source{}, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if ok && (found < 0 || sourceVersion(s).GreaterThan(sourceVersion(candidates[found]))) {
+			found = i
+		}
+	}
+
+	if found < 0 {
+		constraints := make([]string, 0, len(reqs))
+
+		for _, r := range reqs {
+			constraints = append(constraints, r.String())
+		}
+
+		return Source{}, fmt.Errorf("%s: %w: %s", name, ErrDependencyConflict, strings.Join(constraints, ", "))
+	}
+
+	return candidates[found], nil
+}
+
+func satisfies(s Source, reqs []requirement) (bool, error) {
+	for _, r := range reqs {
+		if r.constraint == "" || r.constraint == "*" || r.constraint == latestVersion {
+			continue
+		}
+
+		c, err := parseConstraint(r.constraint)
+		if err != nil {
+			return false, err
+		}
+
+		if v, err := semver.NewVersion(s.Plugin.Version); err != nil || !c.Check(v) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// sortTopologically sorts the plugins so that every plugin comes after its dependencies.
+func sortTopologically(selected map[string]Source) ([]Source, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	var (
+		result = make([]Source, 0, len(selected))
+		state  = make(map[string]int, len(selected))
+		visit  func(name string, path []string) error
+	)
+
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("%s: %w", strings.Join(path, " -> "), ErrDependencyCycle)
+
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		s := selected[name]
+
+		for _, dep := range dependencies(s) {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+
+		state[name] = visited
+		result = append(result, s)
+
+		return nil
+	}
+
+	names := make([]string, 0, len(selected))
+
+	for name := range selected {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// dependencies returns the names of the dependencies of a plugin, sorted.
+func dependencies(s Source) []string {
+	names := make([]string, 0, len(s.metadata.Dependencies))
+
+	for name := range s.metadata.Dependencies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,151 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstaller_InstallWithDependencies(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"/src1/.plugin.registry.yaml": `plugins:
+  - name: app
+    version: 1.0.0
+    dependencies:
+      lib: ^1.0
+      util: "*"
+  - name: util
+  - name: old-lib-user
+    dependencies:
+      lib: ~1.0.0
+  - name: conflict
+    dependencies:
+      app: "*"
+      lib: ^2
+  - name: missing
+    dependencies:
+      nope: ^1
+  - name: b
+    dependencies:
+      a: <2
+  - name: cycle-a
+    dependencies:
+      cycle-b: "*"
+  - name: cycle-b
+    dependencies:
+      cycle-c: "*"
+  - name: cycle-c
+    dependencies:
+      cycle-a: "*"
+`,
+		"/src1/app/app":                          "#!/bin/bash\n",
+		"/src1/util/util":                        "#!/bin/bash\n",
+		"/src1/old-lib-user/old-lib-user":        "#!/bin/bash\n",
+		"/src1/conflict/conflict":                "#!/bin/bash\n",
+		"/src1/missing/missing":                  "#!/bin/bash\n",
+		"/src1/b/b":                              "#!/bin/bash\n",
+		"/src1/cycle-a/cycle-a":                  "#!/bin/bash\n",
+		"/src1/cycle-b/cycle-b":                  "#!/bin/bash\n",
+		"/src1/cycle-c/cycle-c":                  "#!/bin/bash\n",
+		"/src2/lib-1.0/.plugin.registry.yaml":    "name: lib\nversion: 1.0.0\n",
+		"/src2/lib-1.0/lib/lib":                  "#!/bin/bash\n# 1.0.0\n",
+		"/src2/lib-1.2/.plugin.registry.yaml":    "name: lib\nversion: 1.2.0\n",
+		"/src2/lib-1.2/lib/lib":                  "#!/bin/bash\n# 1.2.0\n",
+		"/src2/lib-2.0-rc/.plugin.registry.yaml": "name: lib\nversion: 2.0.0-rc.1\n",
+		"/src2/lib-2.0-rc/lib/lib":               "#!/bin/bash\n# 2.0.0-rc.1\n",
+		"/src2/a-1/.plugin.registry.yaml":        "name: a\nversion: 1.0.0\n",
+		"/src2/a-1/a/a":                          "#!/bin/bash\n",
+		"/src2/a-2/.plugin.registry.yaml":        "name: a\nversion: 2.0.0\ndependencies:\n  gone: \"*\"\n",
+		"/src2/a-2/a/a":                          "#!/bin/bash\n",
+		"/src2/not-a-plugin/README.md":           "# not a plugin\n",
+	}
+
+	testCases := []struct {
+		scenario        string
+		names           []string
+		expected        []string
+		expectedVersion map[string]string
+		expectedError   string
+	}{
+		{
+			scenario:        "dependencies first",
+			names:           []string{"app"},
+			expected:        []string{"lib", "util", "app"},
+			expectedVersion: map[string]string{"lib": "1.2.0"},
+		},
+		{
+			scenario:        "highest version satisfying all constraints",
+			names:           []string{"app", "old-lib-user"},
+			expected:        []string{"lib", "util", "app", "old-lib-user"},
+			expectedVersion: map[string]string{"lib": "1.0.0"},
+		},
+		{
+			scenario:        "lower version drops its dependencies",
+			names:           []string{"a", "b"},
+			expected:        []string{"a", "b"},
+			expectedVersion: map[string]string{"a": "1.0.0"},
+		},
+		{
+			scenario:        "lower version drops its dependencies in any order",
+			names:           []string{"b", "a"},
+			expected:        []string{"a", "b"},
+			expectedVersion: map[string]string{"a": "1.0.0"},
+		},
+		{
+			scenario:      "dependency of the highest version is missing",
+			names:         []string{"a"},
+			expectedError: "could not resolve dependencies: gone * (required by a): plugin does not exist",
+		},
+		{
+			scenario:      "conflict",
+			names:         []string{"conflict"},
+			expectedError: "could not resolve dependencies: lib: dependency conflict: ^2 (required by conflict), ^1.0 (required by app)",
+		},
+		{
+			scenario:      "missing dependency",
+			names:         []string{"missing"},
+			expectedError: "could not resolve dependencies: nope ^1 (required by missing): plugin does not exist",
+		},
+		{
+			scenario:      "unknown plugin",
+			names:         []string{"unknown"},
+			expectedError: "could not resolve dependencies: unknown *: plugin does not exist",
+		},
+		{
+			scenario:      "cycle",
+			names:         []string{"cycle-b"},
+			expectedError: "could not resolve dependencies: cycle-a -> cycle-b -> cycle-c -> cycle-a: dependency cycle",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := newMultiPluginFs(t, files)
+
+			result, err := NewFsInstaller(fs).InstallWithDependencies(context.Background(), "/app/plugins", []string{"/src1", "/src2"}, tc.names...)
+
+			if tc.expectedError != "" {
+				assert.Nil(t, result)
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, pluginNames(result))
+
+			for _, p := range result {
+				if v, ok := tc.expectedVersion[p.Name]; ok {
+					assert.Equal(t, v, p.Version)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package fs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nhatthm/plugin-registry/installer"
+	"github.com/spf13/afero"
+)
+
+func init() { //nolint: gochecknoinits
+	for _, d := range detectors {
+		d := d
+
+		installer.Register(d.name, d.valid, func(fs afero.Fs) installer.Installer {
+			return d.new(fs)
+		})
+	}
+}
+
+// detector detects whether an installer supports a path.
+type detector struct {
+	name  string
+	valid installer.Validity
+	parse func(fs afero.Fs, path string) error
+	new   func(fs afero.Fs, opts ...Option) installer.Installer
+}
+
+// detectors are the installers of this package, they are registered in this order.
+var detectors = []detector{
+	{
+		name:  "fs",
+		valid: isFsPlugin,
+		parse: func(fs afero.Fs, path string) error {
+			_, _, err := parseFsPlugin(fs, path)
+
+			return err
+		},
+		new: func(fs afero.Fs, opts ...Option) installer.Installer {
+			return NewFsInstaller(fs, opts...)
+		},
+	},
+	{
+		name:  "zip",
+		valid: isZipPlugin,
+		parse: func(fs afero.Fs, path string) error {
+			_, _, err := parseZipPath(fs, path)
+
+			return err
+		},
+		new: func(fs afero.Fs, opts ...Option) installer.Installer {
+			return NewZipInstaller(fs, opts...)
+		},
+	},
+	{
+		name:  "gzip",
+		valid: isGzipPlugin,
+		parse: func(fs afero.Fs, path string) error {
+			_, _, err := parseGzipPath(fs, path)
+
+			return err
+		},
+		new: func(fs afero.Fs, opts ...Option) installer.Installer {
+			return NewGzipInstaller(fs, opts...)
+		},
+	},
+}
+
+// Diagnosis is the result of an installer detection on a path.
+type Diagnosis struct {
+	// Installer is the name of the installer, "fs", "zip" or "gzip".
+	Installer string
+	// Err is the reason why the installer does not support the path, or nil if it does.
+	Err error
+}
+
+// Supported checks whether the installer supports the path.
+func (d Diagnosis) Supported() bool {
+	return d.Err == nil
+}
+
+// String returns the installer and the reason, for example "zip: plugin is not a zip".
+func (d Diagnosis) String() string {
+	if d.Err == nil {
+		return fmt.Sprintf("%s: supported", d.Installer)
+	}
+
+	return fmt.Sprintf("%s: %s", d.Installer, d.Err)
+}
+
+// DetectionError indicates that no installer supports a path.
+type DetectionError struct {
+	Path      string
+	Diagnoses []Diagnosis
+}
+
+// Error satisfies the error interface.
+func (e *DetectionError) Error() string {
+	reasons := make([]string, 0, len(e.Diagnoses))
+
+	for _, d := range e.Diagnoses {
+		reasons = append(reasons, d.String())
+	}
+
+	return fmt.Sprintf("%s: %s", installer.ErrNoInstaller, strings.Join(reasons, "; "))
+}
+
+// Is satisfies errors.Is.
+func (e *DetectionError) Is(target error) bool {
+	return target == installer.ErrNoInstaller //nolint: errorlint,goerr113
+}
+
+// Diagnose runs the detector of every installer of this package against the path, and tells why each of them supports
+// it or not.
+func Diagnose(fs afero.Fs, path string) []Diagnosis {
+	result := make([]Diagnosis, 0, len(detectors))
+
+	for _, d := range detectors {
+		result = append(result, Diagnosis{Installer: d.name, Err: d.parse(fs, path)})
+	}
+
+	return result
+}
+
+// newInstallerFor returns the first installer that supports the source, or a DetectionError.
+func newInstallerFor(fs afero.Fs, path string, opts ...Option) (installer.Installer, error) { //nolint: ireturn
+	diagnoses := Diagnose(fs, path)
+
+	for i, d := range diagnoses {
+		if d.Supported() {
+			return detectors[i].new(fs, opts...), nil
+		}
+	}
+
+	return nil, &DetectionError{Path: path, Diagnoses: diagnoses}
+}
@@ -0,0 +1,101 @@
+package fs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nhatthm/plugin-registry/installer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		path     string
+		expected []string
+	}{
+		{
+			scenario: "not exist",
+			path:     "/src/unknown",
+			expected: []string{
+				"fs: open /src/unknown: file does not exist",
+				"zip: open /src/unknown: file does not exist",
+				"gzip: open /src/unknown: file does not exist",
+			},
+		},
+		{
+			scenario: "fs",
+			path:     "/src/fs",
+			expected: []string{
+				"fs: supported",
+				"zip: plugin is a directory",
+				"gzip: plugin is a directory",
+			},
+		},
+		{
+			scenario: "zip",
+			path:     "/src/fs/my-plugin.zip",
+			expected: []string{
+				"fs: plugin is not a directory",
+				"zip: supported",
+				"gzip: plugin is not a gzip",
+			},
+		},
+		{
+			scenario: "gzip without metadata",
+			path:     "/src/archive/my-plugin.tar.gz",
+			expected: []string{
+				"fs: plugin is not a directory",
+				"zip: plugin is not a zip",
+				"gzip: plugin has no metadata: open /src/archive/.plugin.registry.yaml: file does not exist",
+			},
+		},
+	}
+
+	fs := newMultiPluginFs(t, map[string]string{
+		"/src/fs/.plugin.registry.yaml": "name: my-plugin",
+		"/src/fs/my-plugin/my-plugin":   "#!/bin/bash\n",
+		"/src/fs/my-plugin.zip":         "",
+		"/src/archive/my-plugin.tar.gz": "",
+	})
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			diagnoses := Diagnose(fs, tc.path)
+			actual := make([]string, 0, len(diagnoses))
+
+			for _, d := range diagnoses {
+				actual = append(actual, d.String())
+			}
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestNewInstallerFor_DetectionError(t *testing.T) {
+	t.Parallel()
+
+	fs := newMultiPluginFs(t, map[string]string{"/src/readme.md": ""})
+
+	i, err := newInstallerFor(fs, "/src/readme.md")
+
+	assert.Nil(t, i)
+	assert.ErrorIs(t, err, installer.ErrNoInstaller)
+
+	expected := "no supported installer: fs: plugin is not a directory; zip: plugin is not a zip; gzip: plugin is not a gzip"
+
+	require.EqualError(t, err, expected)
+
+	var detectionErr *DetectionError
+
+	require.True(t, errors.As(err, &detectionErr))
+	assert.Equal(t, "/src/readme.md", detectionErr.Path)
+	assert.Len(t, detectionErr.Diagnoses, 3)
+}
@@ -0,0 +1,53 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicateEntry indicates that the archive has more than one entry for the same path.
+var ErrDuplicateEntry = errors.New("duplicate archive entry")
+
+// DuplicatePolicy defines what to do when an archive has more than one entry for the same file.
+type DuplicatePolicy int
+
+const (
+	// DuplicateLastWins replaces the file by the last entry.
+	DuplicateLastWins DuplicatePolicy = iota
+	// DuplicateFirstWins keeps the file of the first entry and ignores the others.
+	DuplicateFirstWins
+	// DuplicateError fails the installation.
+	DuplicateError
+)
+
+// WithDuplicatePolicy sets the policy for the archives having more than one entry for the same file. By default, the
+// last entry wins.
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(c *config) {
+		c.duplicatePolicy = p
+	}
+}
+
+// entries tracks the extracted files of an archive.
+type entries map[string]struct{}
+
+// skip checks whether the file at the path should be skipped according to the duplicate policy.
+func (e entries) skip(p DuplicatePolicy, path string) (bool, error) {
+	if _, ok := e[path]; !ok {
+		e[path] = struct{}{}
+
+		return false, nil
+	}
+
+	switch p {
+	case DuplicateFirstWins:
+		return true, nil
+
+	case DuplicateError:
+		return false, fmt.Errorf("%s: %w", path, ErrDuplicateEntry)
+
+	case DuplicateLastWins:
+	}
+
+	return false, nil
+}
@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveInstaller_Install_DuplicatePolicy(t *testing.T) {
+	t.Parallel()
+
+	files := []archiveEntry{
+		{name: "my-plugin/my-plugin", content: "#!/bin/bash\necho 'first, and longer'\n"},
+		{name: "my-plugin/my-plugin", content: "#!/bin/bash\n"},
+	}
+
+	archives := []struct {
+		name      string
+		content   []byte
+		installer func(fs afero.Fs, opts ...Option) *ArchiveInstaller
+	}{
+		{name: "my-plugin.tar.gz", content: newTarGz(t, files...), installer: NewGzipInstaller},
+		{name: "my-plugin.zip", content: newZip(t, files...), installer: NewZipInstaller},
+	}
+
+	testCases := []struct {
+		scenario      string
+		policy        DuplicatePolicy
+		expected      string
+		expectedError error
+	}{
+		{
+			scenario: "last wins",
+			policy:   DuplicateLastWins,
+			expected: "#!/bin/bash\n",
+		},
+		{
+			scenario: "first wins",
+			policy:   DuplicateFirstWins,
+			expected: "#!/bin/bash\necho 'first, and longer'\n",
+		},
+		{
+			scenario:      "error",
+			policy:        DuplicateError,
+			expectedError: ErrDuplicateEntry,
+		},
+	}
+
+	for _, a := range archives {
+		a := a
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(a.name+"/"+tc.scenario, func(t *testing.T) {
+				t.Parallel()
+
+				fs := afero.NewMemMapFs()
+
+				require.NoError(t, afero.WriteFile(fs, "/tmp/.plugin.registry.yaml", []byte("name: my-plugin"), 0o644))
+				require.NoError(t, afero.WriteFile(fs, "/tmp/"+a.name, a.content, 0o644))
+
+				i := a.installer(fs, WithDuplicatePolicy(tc.policy))
+
+				_, err := i.Install(context.Background(), "/app/plugins", "/tmp/"+a.name)
+
+				if tc.expectedError != nil {
+					require.ErrorIs(t, err, tc.expectedError)
+
+					return
+				}
+
+				require.NoError(t, err)
+
+				data, err := afero.ReadFile(fs, "/app/plugins/my-plugin/my-plugin")
+				require.NoError(t, err)
+
+				assert.Equal(t, tc.expected, string(data))
+			})
+		}
+	}
+}
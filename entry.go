@@ -0,0 +1,49 @@
+package fs
+
+import "strings"
+
+// WithStripComponents strips the given number of leading path components from the archive entries, like
+// tar --strip-components. The entries that have fewer components are not extracted. When it is set, the default
+// stripping of the top-level "<name>/" folder is disabled.
+func WithStripComponents(n int) Option {
+	return func(c *config) {
+		c.stripComponents = n
+	}
+}
+
+// WithSubPath selects a sub-path inside the archive as the plugin root, the entries outside the sub-path are not
+// extracted. It is applied before WithStripComponents. When it is set, the default stripping of the top-level "<name>/"
+// folder is disabled.
+func WithSubPath(path string) Option {
+	return func(c *config) {
+		c.subPath = strings.Trim(path, "/")
+	}
+}
+
+// entryPath returns the path of an archive entry relative to the plugin root, and false if the entry is not a part of
+// the plugin.
+func (c *config) entryPath(pluginDir, name string) (string, bool) {
+	if c.subPath == "" && c.stripComponents <= 0 {
+		return strings.TrimPrefix(name, pluginDir), true
+	}
+
+	if c.subPath != "" {
+		if name != c.subPath && !strings.HasPrefix(name, c.subPath+"/") {
+			return "", false
+		}
+
+		name = strings.TrimPrefix(strings.TrimPrefix(name, c.subPath), "/")
+	}
+
+	if c.stripComponents > 0 {
+		parts := strings.SplitN(name, "/", c.stripComponents+1)
+
+		if len(parts) <= c.stripComponents {
+			return "", false
+		}
+
+		name = parts[c.stripComponents]
+	}
+
+	return name, true
+}
@@ -0,0 +1,159 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_EntryPath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario   string
+		opts       []Option
+		name       string
+		expected   string
+		expectedOk bool
+	}{
+		{
+			scenario:   "default strips plugin dir",
+			name:       "my-plugin/my-plugin",
+			expected:   "my-plugin",
+			expectedOk: true,
+		},
+		{
+			scenario:   "default keeps other paths",
+			name:       "bin/my-plugin",
+			expected:   "bin/my-plugin",
+			expectedOk: true,
+		},
+		{
+			scenario:   "strip components",
+			opts:       []Option{WithStripComponents(2)},
+			name:       "dist/my-plugin_1.0.0_linux_amd64/my-plugin",
+			expected:   "my-plugin",
+			expectedOk: true,
+		},
+		{
+			scenario: "strip components of a short path",
+			opts:     []Option{WithStripComponents(2)},
+			name:     "dist/README.md",
+		},
+		{
+			scenario:   "sub path",
+			opts:       []Option{WithSubPath("/dist/linux/")},
+			name:       "dist/linux/my-plugin",
+			expected:   "my-plugin",
+			expectedOk: true,
+		},
+		{
+			scenario:   "sub path itself",
+			opts:       []Option{WithSubPath("dist/linux")},
+			name:       "dist/linux/",
+			expected:   "",
+			expectedOk: true,
+		},
+		{
+			scenario: "outside sub path",
+			opts:     []Option{WithSubPath("dist/linux")},
+			name:     "dist/linux-arm64/my-plugin",
+		},
+		{
+			scenario:   "sub path and strip components",
+			opts:       []Option{WithSubPath("dist"), WithStripComponents(1)},
+			name:       "dist/my-plugin_1.0.0_linux_amd64/my-plugin",
+			expected:   "my-plugin",
+			expectedOk: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := newConfig(afero.NewMemMapFs(), tc.opts...)
+
+			actual, ok := cfg.entryPath("my-plugin/", tc.name)
+
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expectedOk, ok)
+		})
+	}
+}
+
+func TestArchiveInstaller_Install_StripComponents(t *testing.T) {
+	t.Parallel()
+
+	files := []archiveEntry{
+		{name: "my-plugin_1.0.0_linux_amd64/my-plugin", content: "#!/bin/bash\n"},
+		{name: "my-plugin_1.0.0_linux_amd64/docs/README.md", content: "# my-plugin\n"},
+		{name: "LICENSE", content: "MIT\n"},
+	}
+
+	testCases := []struct {
+		scenario  string
+		name      string
+		content   []byte
+		installer func(fs afero.Fs, opts ...Option) *ArchiveInstaller
+		opts      []Option
+	}{
+		{
+			scenario:  "gzip strip components",
+			name:      "my-plugin.tar.gz",
+			content:   newTarGz(t, files...),
+			installer: NewGzipInstaller,
+			opts:      []Option{WithStripComponents(1)},
+		},
+		{
+			scenario:  "zip strip components",
+			name:      "my-plugin.zip",
+			content:   newZip(t, files...),
+			installer: NewZipInstaller,
+			opts:      []Option{WithStripComponents(1)},
+		},
+		{
+			scenario:  "gzip sub path",
+			name:      "my-plugin.tar.gz",
+			content:   newTarGz(t, files...),
+			installer: NewGzipInstaller,
+			opts:      []Option{WithSubPath("my-plugin_1.0.0_linux_amd64")},
+		},
+		{
+			scenario:  "zip sub path",
+			name:      "my-plugin.zip",
+			content:   newZip(t, files...),
+			installer: NewZipInstaller,
+			opts:      []Option{WithSubPath("my-plugin_1.0.0_linux_amd64")},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			require.NoError(t, afero.WriteFile(fs, "/tmp/.plugin.registry.yaml", []byte("name: my-plugin"), 0o644))
+			require.NoError(t, afero.WriteFile(fs, "/tmp/"+tc.name, tc.content, 0o644))
+
+			_, err := tc.installer(fs, tc.opts...).Install(context.Background(), "/app/plugins", "/tmp/"+tc.name)
+			require.NoError(t, err)
+
+			data, err := afero.ReadFile(fs, "/app/plugins/my-plugin/docs/README.md")
+			require.NoError(t, err)
+
+			assert.Equal(t, "# my-plugin\n", string(data))
+
+			exists, err := afero.Exists(fs, "/app/plugins/my-plugin/LICENSE")
+			require.NoError(t, err)
+
+			assert.False(t, exists)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package fs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bool64/ctxd"
+)
+
+// Phase is the phase of an installation in which an error occurs.
+type Phase string
+
+const (
+	// PhaseDetect is when the source is found and its installer is chosen.
+	PhaseDetect Phase = "detect"
+	// PhaseParse is when the plugin metadata is read.
+	PhaseParse Phase = "parse"
+	// PhaseLock is when the destination is locked.
+	PhaseLock Phase = "lock"
+	// PhaseExtract is when the plugin is copied or extracted to the destination.
+	PhaseExtract Phase = "extract"
+	// PhaseFinalize is when an extracted file is moved to its final path, or the installation is recorded.
+	PhaseFinalize Phase = "finalize"
+	// PhaseVerify is when the installed plugin is validated.
+	PhaseVerify Phase = "verify"
+	// PhaseHook is when the hooks of the plugin run.
+	PhaseHook Phase = "hook"
+)
+
+// InstallError is the error of an installation. The installers return it, wrapped with a message, for every failure
+// so that it can be found with errors.As.
+type InstallError struct {
+	// Phase is the phase of the installation in which the error occurs.
+	Phase Phase
+	// Source is the path of the plugin source.
+	Source string
+	// Entry is the name of the archive entry, if any, that causes the error.
+	Entry string
+	// Err is the cause of the error.
+	Err error
+}
+
+// Error returns the message of the cause so that the messages are the same with or without the InstallError.
+func (e *InstallError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause of the error.
+func (e *InstallError) Unwrap() error {
+	return e.Err
+}
+
+// installError wraps the error with an InstallError of the phase and the source, and then with the message, unless
+// the error is already an InstallError, in which case only the missing source is set.
+func installError(ctx context.Context, phase Phase, source string, err error, message string, keysAndValues ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	var ie *InstallError
+
+	if errors.As(err, &ie) {
+		if ie.Source == "" {
+			ie.Source = source
+		}
+	} else {
+		err = &InstallError{Phase: phase, Source: source, Err: err}
+	}
+
+	return ctxd.WrapError(ctx, err, message, keysAndValues...)
+}
+
+// entryError annotates the error with the archive entry that causes it. The phase is PhaseExtract unless it is already
+// set.
+func entryError(name string, err error) error {
+	var ie *InstallError
+
+	if errors.As(err, &ie) {
+		if ie.Entry == "" {
+			ie.Entry = name
+		}
+
+		return err
+	}
+
+	return &InstallError{Phase: PhaseExtract, Entry: name, Err: err}
+}
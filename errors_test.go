@@ -0,0 +1,120 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		files         map[string]string
+		archive       []byte
+		source        string
+		expected      InstallError
+		expectedCause error
+	}{
+		{
+			scenario: "not a directory",
+			files:    map[string]string{"/src": ""},
+			source:   "/src",
+			expected: InstallError{
+				Phase:  PhaseDetect,
+				Source: "/src",
+			},
+			expectedCause: ErrPluginNotDir,
+		},
+		{
+			scenario: "bad file filter",
+			files: map[string]string{
+				"/src/.plugin.registry.yaml": "name: my-plugin\nfiles:\n  include: ['[a']\n",
+				"/src/my-plugin/my-plugin":   "#!/bin/bash\n",
+			},
+			source: "/src",
+			expected: InstallError{
+				Phase:  PhaseParse,
+				Source: "/src",
+			},
+			expectedCause: doublestar.ErrBadPattern,
+		},
+		{
+			scenario: "illegal entry",
+			files: map[string]string{
+				"/src/.plugin.registry.yaml": "name: my-plugin",
+			},
+			archive: newZip(t, archiveEntry{name: "my-plugin/../../evil", content: "#!/bin/bash\n"}),
+			source:  "/src/my-plugin.zip",
+			expected: InstallError{
+				Phase:  PhaseExtract,
+				Source: "/src/my-plugin.zip",
+				Entry:  "my-plugin/../../evil",
+			},
+			expectedCause: ErrIllegalFilePath,
+		},
+		{
+			scenario: "entrypoint is not executable",
+			files: map[string]string{
+				"/src/.plugin.registry.yaml": "name: my-plugin",
+			},
+			archive: newZip(t, archiveEntry{name: "my-plugin/my-plugin", content: "#!/bin/bash\n", mode: 0o644}),
+			source:  "/src/my-plugin.zip",
+			expected: InstallError{
+				Phase:  PhaseVerify,
+				Source: "/src/my-plugin.zip",
+			},
+			expectedCause: ErrEntrypointNotExecutable,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := newMultiPluginFs(t, tc.files)
+
+			if tc.archive != nil {
+				require.NoError(t, afero.WriteFile(fs, tc.source, tc.archive, 0o644))
+			}
+
+			i, err := newInstallerFor(fs, tc.source)
+			if err != nil {
+				i = NewFsInstaller(fs)
+			}
+
+			_, err = i.Install(context.Background(), "/app/plugins", tc.source)
+
+			var ie *InstallError
+
+			require.True(t, errors.As(err, &ie))
+			assert.Equal(t, tc.expected.Phase, ie.Phase)
+			assert.Equal(t, tc.expected.Source, ie.Source)
+			assert.Equal(t, tc.expected.Entry, ie.Entry)
+			assert.ErrorIs(t, err, tc.expectedCause)
+			assert.Equal(t, ie.Err.Error(), ie.Error())
+		})
+	}
+}
+
+func TestInstallError_Bulk(t *testing.T) {
+	t.Parallel()
+
+	fs := newMultiPluginFs(t, map[string]string{"/src/readme.md": ""})
+
+	results, err := NewBulk(fs, 1).Install(context.Background(), "/app/plugins", []string{"/src/readme.md"})
+	require.Error(t, err)
+
+	var ie *InstallError
+
+	require.True(t, errors.As(results[0].Err, &ie))
+	assert.Equal(t, PhaseDetect, ie.Phase)
+	assert.Equal(t, "/src/readme.md", ie.Source)
+}
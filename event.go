@@ -0,0 +1,65 @@
+package fs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+)
+
+// InstallEvent is an event of the installation of a plugin.
+type InstallEvent struct {
+	// Plugin is the plugin that is installed.
+	Plugin *plugin.Plugin
+	// Dest is the destination directory, the plugin is installed in Dest/<name>.
+	Dest string
+	// Source is the path given to the installer.
+	Source string
+	// Err is the error of the installation, for InstallFailed only.
+	Err error
+}
+
+// InstallEventHandler handles an install event. The handlers are called synchronously, in the order in which they are
+// added.
+type InstallEventHandler func(ctx context.Context, e InstallEvent)
+
+// events are the handlers of the install events, shared by the copies of a config.
+type events struct {
+	mu sync.RWMutex
+
+	before []InstallEventHandler
+	after  []InstallEventHandler
+	failed []InstallEventHandler
+}
+
+// OnBeforeInstall adds a handler that is called before a plugin is installed, once it is detected.
+func (c *config) OnBeforeInstall(h InstallEventHandler) {
+	c.events.add(&c.events.before, h)
+}
+
+// OnAfterInstall adds a handler that is called after a plugin is installed and validated.
+func (c *config) OnAfterInstall(h InstallEventHandler) {
+	c.events.add(&c.events.after, h)
+}
+
+// OnInstallFailed adds a handler that is called when the installation of a plugin fails, after it is rolled back.
+func (c *config) OnInstallFailed(h InstallEventHandler) {
+	c.events.add(&c.events.failed, h)
+}
+
+func (e *events) add(handlers *[]InstallEventHandler, h InstallEventHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	*handlers = append(*handlers, h)
+}
+
+func (e *events) emit(ctx context.Context, handlers *[]InstallEventHandler, event InstallEvent) {
+	e.mu.RLock()
+	hs := *handlers
+	e.mu.RUnlock()
+
+	for _, h := range hs {
+		h(ctx, event)
+	}
+}
@@ -0,0 +1,72 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type eventRecorder struct {
+	events []string
+}
+
+func (r *eventRecorder) handler(name string) InstallEventHandler {
+	return func(_ context.Context, e InstallEvent) {
+		event := name + " " + e.Plugin.Name + " " + e.Dest + " " + e.Source
+
+		if e.Err != nil {
+			event += ": " + e.Err.Error()
+		}
+
+		r.events = append(r.events, event)
+	}
+}
+
+func TestInstaller_Events(t *testing.T) {
+	t.Parallel()
+
+	fs := newMultiPluginFs(t, map[string]string{
+		"/src/.plugin.registry.yaml": "name: my-plugin",
+		"/src/my-plugin/my-plugin":   "#!/bin/bash\n",
+	})
+
+	r := &eventRecorder{}
+	i := NewFsInstaller(fs)
+
+	i.OnBeforeInstall(r.handler("before"))
+	i.OnAfterInstall(r.handler("after"))
+	i.OnInstallFailed(r.handler("failed"))
+
+	_, err := i.Install(context.Background(), "/app/plugins", "/src")
+	require.NoError(t, err)
+
+	expected := []string{
+		"before my-plugin /app/plugins /src",
+		"after my-plugin /app/plugins /src",
+	}
+
+	assert.Equal(t, expected, r.events)
+}
+
+func TestArchiveInstaller_Events(t *testing.T) {
+	t.Parallel()
+
+	r := &eventRecorder{}
+	i := NewGzipInstaller(newLoggingFs(t, 0o644))
+
+	i.OnBeforeInstall(r.handler("before"))
+	i.OnAfterInstall(r.handler("after"))
+	i.OnInstallFailed(r.handler("failed"))
+
+	_, err := i.Install(context.Background(), "/app/plugins", "/src/my-plugin.tar.gz")
+	require.Error(t, err)
+
+	expected := []string{
+		"before my-plugin /app/plugins /src/my-plugin.tar.gz",
+		"failed my-plugin /app/plugins /src/my-plugin.tar.gz: " + err.Error(),
+	}
+
+	assert.Equal(t, expected, r.events)
+}
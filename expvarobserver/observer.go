@@ -0,0 +1,83 @@
+// Package expvarobserver publishes the metrics of the file system installers with expvar.
+package expvarobserver
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	fs "github.com/nhatthm/plugin-registry-fs"
+)
+
+var _ fs.Observer = (*Observer)(nil)
+
+// Observer publishes the metrics of the installations in an expvar map:
+//
+//	{
+//	    "installs": {"total": 3, "failed": 1},
+//	    "failures": {"locked": 1},
+//	    "duration_seconds": {"install": 1.2, "detect": 0.1, "extract": 1, "verify": 0.1},
+//	    "entries": 42,
+//	    "bytes_read": 1024,
+//	    "bytes_written": 4096
+//	}
+//
+// The durations are the sums of the durations of all the installations.
+type Observer struct {
+	installs     *expvar.Map
+	failures     *expvar.Map
+	durations    *expvar.Map
+	entries      *expvar.Int
+	bytesRead    *expvar.Int
+	bytesWritten *expvar.Int
+}
+
+// New creates a new observer and publishes its map with the name. Like expvar.Publish, it panics if the name is
+// already registered.
+func New(name string) *Observer {
+	o := newObserver()
+	m := expvar.NewMap(name)
+
+	m.Set("installs", o.installs)
+	m.Set("failures", o.failures)
+	m.Set("duration_seconds", o.durations)
+	m.Set("entries", o.entries)
+	m.Set("bytes_read", o.bytesRead)
+	m.Set("bytes_written", o.bytesWritten)
+
+	return o
+}
+
+func newObserver() *Observer {
+	return &Observer{
+		installs:     new(expvar.Map).Init(),
+		failures:     new(expvar.Map).Init(),
+		durations:    new(expvar.Map).Init(),
+		entries:      new(expvar.Int),
+		bytesRead:    new(expvar.Int),
+		bytesWritten: new(expvar.Int),
+	}
+}
+
+// ObserveInstall counts the installations and the failures by kind, see fs.ErrorKind.
+func (o *Observer) ObserveInstall(_ context.Context, _ string, d time.Duration, err error) {
+	o.installs.Add("total", 1)
+	o.durations.AddFloat("install", d.Seconds())
+
+	if err != nil {
+		o.installs.Add("failed", 1)
+		o.failures.Add(fs.ErrorKind(err), 1)
+	}
+}
+
+// ObservePhase adds the duration of the phase.
+func (o *Observer) ObservePhase(_ context.Context, phase fs.Phase, d time.Duration, _ error) {
+	o.durations.AddFloat(string(phase), d.Seconds())
+}
+
+// ObserveEntry counts the entries and their bytes.
+func (o *Observer) ObserveEntry(_ context.Context, _ string, read, written int64) {
+	o.entries.Add(1)
+	o.bytesRead.Add(read)
+	o.bytesWritten.Add(written)
+}
@@ -0,0 +1,80 @@
+package expvarobserver
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	fs "github.com/nhatthm/plugin-registry-fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newZipFs(t *testing.T) afero.Fs {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w := zip.NewWriter(&buf)
+	h := &zip.FileHeader{Name: "my-plugin/my-plugin", Method: zip.Store}
+	h.SetMode(0o755)
+
+	f, err := w.CreateHeader(h)
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("#!/bin/bash\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	memFs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(memFs, "/src/.plugin.registry.yaml", []byte("name: my-plugin"), 0o644))
+	require.NoError(t, afero.WriteFile(memFs, "/src/my-plugin.zip", buf.Bytes(), 0o644))
+
+	return memFs
+}
+
+func TestObserver(t *testing.T) {
+	t.Parallel()
+
+	o := New(t.Name())
+	memFs := newZipFs(t)
+	i := fs.NewZipInstaller(memFs, fs.WithObserver(o))
+
+	_, err := i.Install(context.Background(), "/app/plugins", "/src/my-plugin.zip")
+	require.NoError(t, err)
+
+	_, err = i.Install(context.Background(), "/app/plugins", "/src/unknown.zip")
+	require.Error(t, err)
+
+	var actual struct {
+		Installs     map[string]int64   `json:"installs"`
+		Failures     map[string]int64   `json:"failures"`
+		Durations    map[string]float64 `json:"duration_seconds"`
+		Entries      int64              `json:"entries"`
+		BytesRead    int64              `json:"bytes_read"`
+		BytesWritten int64              `json:"bytes_written"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get(t.Name()).String()), &actual))
+
+	assert.Equal(t, map[string]int64{"total": 2, "failed": 1}, actual.Installs)
+	assert.Equal(t, map[string]int64{"not_found": 1}, actual.Failures)
+	assert.Equal(t, int64(1), actual.Entries)
+	assert.Equal(t, int64(12), actual.BytesWritten)
+
+	// The bytes read from the archive include the header of the entry.
+	fi, err := memFs.Stat("/src/my-plugin.zip")
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, actual.BytesRead, int64(12))
+	assert.LessOrEqual(t, actual.BytesRead, fi.Size())
+
+	for _, name := range []string{"install", "detect", "extract", "verify"} {
+		assert.Contains(t, actual.Durations, name)
+	}
+}
@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// WithInclude only installs the files matching at least one of the doublestar patterns, for example "bin/**". The
+// patterns are matched against the paths relative to the plugin root, and are combined with the ones declared in
+// .plugin.registry.yaml. The filters do not apply to the link mode.
+func WithInclude(patterns ...string) Option {
+	return func(c *config) {
+		c.filter.Include = append(c.filter.Include, patterns...)
+	}
+}
+
+// WithExclude does not install the files and directories matching any of the doublestar patterns, for example
+// "**/*_test.go". The patterns are matched against the paths relative to the plugin root, and are combined with the
+// ones declared in .plugin.registry.yaml. The filters do not apply to the link mode.
+func WithExclude(patterns ...string) Option {
+	return func(c *config) {
+		c.filter.Exclude = append(c.filter.Exclude, patterns...)
+	}
+}
+
+// fileFilter filters the installed files by doublestar patterns.
+type fileFilter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+func (f fileFilter) merge(other fileFilter) fileFilter {
+	return fileFilter{
+		Include: append(append([]string(nil), f.Include...), other.Include...),
+		Exclude: append(append([]string(nil), f.Exclude...), other.Exclude...),
+	}
+}
+
+func (f fileFilter) validate() error {
+	for _, patterns := range [][]string{f.Include, f.Exclude} {
+		for _, p := range patterns {
+			if !doublestar.ValidatePattern(p) {
+				return fmt.Errorf("%s: %w", p, doublestar.ErrBadPattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// skip checks whether the file or directory at the path, relative to the plugin root, is filtered out. A path is
+// excluded with its parent directories, like the folder installs that skip the whole excluded directories. The include
+// patterns only apply to files so that the directories containing the included files are installed.
+func (f fileFilter) skip(path string, isDir bool) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	if path == "." {
+		return false
+	}
+
+	for dir := path; dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if matchAny(f.Exclude, dir) {
+			return true
+		}
+	}
+
+	if isDir || len(f.Include) == 0 {
+		return false
+	}
+
+	return !matchAny(f.Include, path)
+}
+
+func matchAny(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if ok, _ := doublestar.Match(p, path); ok { //nolint: errcheck
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,205 @@
+package fs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileFilter_Skip(t *testing.T) {
+	t.Parallel()
+
+	f := fileFilter{
+		Include: []string{"my-plugin", "lib/**"},
+		Exclude: []string{"**/*.debug", "lib/testdata"},
+	}
+
+	testCases := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{path: "."},
+		{path: "my-plugin"},
+		{path: "README.md", expected: true},
+		{path: "lib", isDir: true},
+		{path: "lib/a.so"},
+		{path: "lib/a.so.debug", expected: true},
+		{path: "lib/testdata", isDir: true, expected: true},
+		{path: "lib/testdata/a.so", expected: true},
+		{path: "lib/testdata/data", isDir: true, expected: true},
+		{path: "docs", isDir: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, f.skip(tc.path, tc.isDir))
+		})
+	}
+}
+
+func TestFileFilter_Validate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, fileFilter{Include: []string{"**/*.so"}}.validate())
+
+	err := fileFilter{Exclude: []string{"[a-"}}.validate()
+
+	require.EqualError(t, err, "[a-: syntax error in pattern")
+}
+
+func TestInstaller_Install_Filter(t *testing.T) {
+	t.Parallel()
+
+	const metadata = "name: my-plugin\nfiles:\n  exclude:\n    - \"**/*_test.sh\"\n"
+
+	files := []archiveEntry{
+		{name: "my-plugin/my-plugin", content: "#!/bin/bash\n"},
+		{name: "my-plugin/my-plugin_test.sh", content: "#!/bin/bash\n"},
+		{name: "my-plugin/README.md", content: "# my-plugin\n"},
+		{name: "my-plugin/lib/helper.sh", content: "#!/bin/bash\n"},
+	}
+
+	setups := map[string]struct {
+		path  string
+		setup func(t *testing.T, fs afero.Fs)
+	}{
+		"fs": {
+			path: "/tmp",
+			setup: func(t *testing.T, fs afero.Fs) {
+				t.Helper()
+
+				for _, f := range files {
+					require.NoError(t, afero.WriteFile(fs, "/tmp/"+f.name, []byte(f.content), 0o755))
+				}
+			},
+		},
+		"zip": {
+			path: "/tmp/my-plugin.zip",
+			setup: func(t *testing.T, fs afero.Fs) {
+				t.Helper()
+
+				require.NoError(t, afero.WriteFile(fs, "/tmp/my-plugin.zip", newZip(t, files...), 0o644))
+			},
+		},
+		"gzip": {
+			path: "/tmp/my-plugin.tar.gz",
+			setup: func(t *testing.T, fs afero.Fs) {
+				t.Helper()
+
+				require.NoError(t, afero.WriteFile(fs, "/tmp/my-plugin.tar.gz", newTarGz(t, files...), 0o644))
+			},
+		},
+	}
+
+	for name, s := range setups {
+		name, s := name, s
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			require.NoError(t, afero.WriteFile(fs, "/tmp/.plugin.registry.yaml", []byte(metadata), 0o644))
+			s.setup(t, fs)
+
+			i := newInstaller(t, name, fs, WithExclude("README.md"))
+
+			_, err := i.Install(context.Background(), "/app/plugins", s.path)
+			require.NoError(t, err)
+
+			for path, expected := range map[string]bool{
+				"/app/plugins/my-plugin/my-plugin":         true,
+				"/app/plugins/my-plugin/lib/helper.sh":     true,
+				"/app/plugins/my-plugin/my-plugin_test.sh": false,
+				"/app/plugins/my-plugin/README.md":         false,
+			} {
+				exists, err := afero.Exists(fs, path)
+				require.NoError(t, err)
+
+				assert.Equal(t, expected, exists, path)
+			}
+		})
+	}
+}
+
+func TestInstaller_Install_ExcludeDirectory(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		source   func(t *testing.T, fs afero.Fs, src string) string
+	}{
+		{
+			scenario: "fs",
+			source: func(_ *testing.T, _ afero.Fs, src string) string {
+				return src
+			},
+		},
+		{
+			scenario: "zip",
+			source: func(t *testing.T, fs afero.Fs, src string) string {
+				t.Helper()
+
+				pkg, err := Pack(fs, src, filepath.Join(filepath.Dir(src), "dist"), FormatZip)
+				require.NoError(t, err)
+
+				return pkg.Archive
+			},
+		},
+		{
+			scenario: "gzip",
+			source: func(t *testing.T, fs afero.Fs, src string) string {
+				t.Helper()
+
+				pkg, err := Pack(fs, src, filepath.Join(filepath.Dir(src), "dist"), FormatTarGz)
+				require.NoError(t, err)
+
+				return pkg.Archive
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewOsFs()
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src")
+			dest := filepath.Join(dir, "plugins")
+
+			for path, content := range map[string]string{
+				".plugin.registry.yaml":   "name: my-plugin\nversion: 1.0.0\n",
+				"my-plugin/my-plugin":     "#!/bin/bash\n",
+				"my-plugin/docs/a.md":     "# a\n",
+				"my-plugin/docs/api/b.md": "# b\n",
+			} {
+				require.NoError(t, fs.MkdirAll(filepath.Dir(filepath.Join(src, path)), 0o755))
+				require.NoError(t, afero.WriteFile(fs, filepath.Join(src, path), []byte(content), 0o755))
+			}
+
+			i := newInstaller(t, tc.scenario, fs, WithExclude("docs"))
+
+			_, err := i.Install(context.Background(), dest, tc.source(t, fs, src))
+			require.NoError(t, err)
+
+			for path, expected := range map[string]bool{
+				"my-plugin/my-plugin": true,
+				"my-plugin/docs":      false,
+			} {
+				exists, err := afero.Exists(fs, filepath.Join(dest, path))
+				require.NoError(t, err)
+
+				assert.Equal(t, expected, exists, path)
+			}
+		})
+	}
+}
@@ -7,12 +7,9 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/bool64/ctxd"
 	fsCtx "github.com/nhatthm/plugin-registry/context"
-	"github.com/nhatthm/plugin-registry/installer"
 	"github.com/nhatthm/plugin-registry/plugin"
 	"github.com/spf13/afero"
-	"go.nhat.io/aferocopy/v2"
 )
 
 var (
@@ -22,39 +19,73 @@ var (
 	ErrPluginIsDir = errors.New("plugin is a directory")
 	// ErrIllegalFilePath indicates that the file path is illegal.
 	ErrIllegalFilePath = errors.New("illegal file path")
+	// ErrPluginNoName indicates that the plugin metadata has no name.
+	ErrPluginNoName = errors.New("plugin has no name")
 )
 
-func init() { //nolint: gochecknoinits
-	installer.Register("fs", isFsPlugin, func(fs afero.Fs) installer.Installer {
-		return NewFsInstaller(fs)
-	})
-}
-
 // Installer is a file system installer.
 type Installer struct {
-	fs afero.Fs
+	config
 }
 
 // Install installs the plugin.
 func (i *Installer) Install(ctx context.Context, dest, path string) (*plugin.Plugin, error) {
-	path, p, err := parseFsPlugin(i.fs, path)
+	var m *metadata
+
+	source := path
+
+	return i.observeInstall(ctx, func(ctx context.Context) (*plugin.Plugin, error) {
+		var (
+			p   *plugin.Plugin
+			err error
+		)
+
+		path, p, err = parseFsPlugin(i.srcFs, path)
+		if err != nil {
+			return nil, installError(ctx, PhaseDetect, source, err, "could not parse plugin path", "path", path)
+		}
+
+		m, err = loadMetadata(i.srcFs, path)
+		if err != nil {
+			return nil, installError(ctx, PhaseParse, path, err, "could not read metadata", "path", path)
+		}
+
+		if i.version != "" {
+			p.Version = i.version
+		}
+
+		i.log(ctx).Debug(ctx, "plugin detected", "installer", "fs", "plugin", p.Name, "path", path)
+
+		return p, nil
+	}, func(ctx context.Context, p *plugin.Plugin) error {
+		return i.install(ctx, dest, path, p, m)
+	})
+}
+
+func (i *Installer) install(ctx context.Context, dest, path string, p *plugin.Plugin, m *metadata) error {
+	cfg, err := i.forPlugin(m)
 	if err != nil {
-		return nil, ctxd.WrapError(ctx, err, "could not parse plugin path", "path", path)
+		return installError(ctx, PhaseParse, path, err, "invalid file filter", "path", path)
 	}
 
-	if err := installFs(i.fs, dest, path, p); err != nil {
-		return nil, ctxd.WrapError(ctx, err, "could not install plugin", "path", path)
+	install := installFs
+	if i.link {
+		install = installLink
 	}
 
-	return p, nil
+	return cfg.run(ctx, dest, path, path, p, func(ctx context.Context, cfg *config, dest string) error {
+		return install(ctx, cfg, dest, path, p)
+	})
 }
 
 // NewFsInstaller creates a new filesystem installer.
-func NewFsInstaller(fs afero.Fs) *Installer {
+func NewFsInstaller(fs afero.Fs, opts ...Option) *Installer {
 	i := &Installer{
-		fs: fs,
+		config: newConfig(fs, opts...),
 	}
 
+	i.kind = "fs"
+
 	return i
 }
 
@@ -81,6 +112,10 @@ func parseFsPlugin(fs afero.Fs, path string) (string, *plugin.Plugin, error) {
 		return "", nil, err
 	}
 
+	if p.Name == "" {
+		return "", nil, ErrPluginNoName
+	}
+
 	pluginPath := filepath.Join(path, p.Name)
 
 	if _, err := fs.Stat(pluginPath); err != nil {
@@ -90,20 +125,21 @@ func parseFsPlugin(fs afero.Fs, path string) (string, *plugin.Plugin, error) {
 	return path, p, nil
 }
 
-func installFs(fs afero.Fs, dest, src string, p *plugin.Plugin) error {
+func installFs(ctx context.Context, cfg *config, dest, src string, p *plugin.Plugin) error {
 	src = filepath.Join(src, p.Name)
 	dest = filepath.Join(dest, p.Name)
 
-	if err := recreatePath(fs, dest); err != nil {
+	if err := recreatePath(cfg.destFs, dest); err != nil {
 		return err
 	}
 
-	if isDir, _ := afero.IsDir(fs, src); !isDir { //nolint: errcheck
+	if isDir, _ := afero.IsDir(cfg.srcFs, src); !isDir { //nolint: errcheck
 		dest = filepath.Join(dest, p.Name)
 	}
 
-	return aferocopy.Copy(src, dest, aferocopy.Options{
-		SrcFs:         fs,
-		PreserveTimes: true,
-	})
+	if canDedup(cfg) {
+		return dedupCopy(ctx, cfg, src, dest)
+	}
+
+	return copyPath(ctx, cfg, src, dest)
 }
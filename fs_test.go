@@ -278,6 +278,11 @@ func TestFsInstaller_Install_Error(t *testing.T) {
 				fs.On("Stat", "/tmp/my-plugin").
 					Return(aferomock.NopFileInfo(t), nil)
 
+				expectInstallLock(fs, "/app/plugins", "my-plugin")
+
+				fs.On("Stat", "/app/plugins/my-plugin").
+					Return(aferomock.NopFileInfo(t), nil)
+
 				fs.On("RemoveAll", mock.Anything).
 					Return(errors.New("remove error"))
 			}),
@@ -304,7 +309,12 @@ func TestFsInstaller_Install_Error(t *testing.T) {
 				fs.On("RemoveAll", mock.Anything).
 					Return(nil)
 
-				fs.On("MkdirAll", mock.Anything, os.FileMode(0o755)).
+				expectInstallLock(fs, "/app/plugins", "my-plugin")
+
+				fs.On("Stat", "/app/plugins/my-plugin").
+					Return(aferomock.NopFileInfo(t), nil)
+
+				fs.On("MkdirAll", "/app/plugins/.my-plugin.staging/my-plugin", os.FileMode(0o755)).
 					Return(errors.New("mkdir error"))
 			}),
 			expectedError: "could not install plugin: mkdir error",
@@ -327,10 +337,15 @@ func TestFsInstaller_Install_Error(t *testing.T) {
 				fs.On("Stat", "/tmp/my-plugin").Once().
 					Return(aferomock.NopFileInfo(t), nil)
 
+				expectInstallLock(fs, "/app/plugins", "my-plugin")
+
+				fs.On("Stat", "/app/plugins/my-plugin").
+					Return(aferomock.NopFileInfo(t), nil)
+
 				fs.On("RemoveAll", mock.Anything).
 					Return(nil)
 
-				fs.On("MkdirAll", mock.Anything, os.FileMode(0o755)).
+				fs.On("MkdirAll", "/app/plugins/.my-plugin.staging/my-plugin", os.FileMode(0o755)).
 					Return(nil)
 
 				fs.On("Stat", "/tmp/my-plugin").
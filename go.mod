@@ -3,12 +3,15 @@ module github.com/nhatthm/plugin-registry-fs
 go 1.17
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
+	github.com/bmatcuk/doublestar/v4 v4.10.2
 	github.com/bool64/ctxd v1.2.1
 	github.com/nhatthm/plugin-registry v0.4.0
 	github.com/spf13/afero v1.11.0
 	github.com/stretchr/testify v1.10.0
 	go.nhat.io/aferocopy/v2 v2.0.2
 	go.nhat.io/aferomock v0.7.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -16,5 +19,4 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	golang.org/x/text v0.18.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -12,7 +12,6 @@ import (
 	"strings"
 
 	fsCtx "github.com/nhatthm/plugin-registry/context"
-	"github.com/nhatthm/plugin-registry/installer"
 	"github.com/nhatthm/plugin-registry/plugin"
 	"github.com/spf13/afero"
 )
@@ -20,21 +19,17 @@ import (
 // ErrPluginNotGzip indicates that the plugin is not a zip.
 var ErrPluginNotGzip = errors.New("plugin is not a gzip")
 
-func init() { //nolint: gochecknoinits
-	installer.Register("gzip", isGzipPlugin, func(fs afero.Fs) installer.Installer {
-		return NewGzipInstaller(fs)
-	})
-}
-
-// NewGzipInstaller creates a new filesystem installer.
-func NewGzipInstaller(fs afero.Fs) *ArchiveInstaller {
+// NewGzipInstaller creates a new gzip installer.
+func NewGzipInstaller(fs afero.Fs, opts ...Option) *ArchiveInstaller {
 	i := &ArchiveInstaller{
-		fs: fs,
+		config: newConfig(fs, opts...),
 
 		parseURL: parseGzipPath,
 		install:  installGzip,
 	}
 
+	i.kind = "gzip"
+
 	return i
 }
 
@@ -64,14 +59,17 @@ func parseGzipPath(fs afero.Fs, path string) (string, string, error) { //nolint:
 	return path, metadataPath, nil
 }
 
-func installGzip(fs afero.Fs, dst string, p plugin.Plugin, tarFile string) error {
-	fi, r, err := openPluginFile(fs, tarFile)
+func installGzip(ctx context.Context, cfg *config, dst string, p plugin.Plugin, tarFile string) error {
+	fi, r, err := openPluginFile(cfg.srcFs, tarFile)
 	if err != nil {
 		return err
 	}
 	defer r.Close() //nolint: errcheck
 
-	gzr, err := gzip.NewReader(r)
+	// The bytes read from the archive are counted for every entry.
+	src := &countingReader{Reader: r}
+
+	gzr, err := gzip.NewReader(src)
 	if err != nil {
 		return err
 	}
@@ -80,18 +78,40 @@ func installGzip(fs afero.Fs, dst string, p plugin.Plugin, tarFile string) error
 	pluginDir := fmt.Sprintf("%s%c", p.Name, os.PathSeparator)
 	dst = filepath.Join(dst, p.Name)
 
-	if err := recreatePath(fs, dst); err != nil {
+	if err := recreatePath(cfg.destFs, dst); err != nil {
 		return err
 	}
 
 	if strings.HasSuffix(tarFile, ".tar.gz") {
-		return extractTar(fs, dst, pluginDir, tar.NewReader(gzr))
+		return extractTar(ctx, cfg, dst, pluginDir, tar.NewReader(gzr), src)
+	}
+
+	path := filepath.Join(dst, p.Name)
+
+	mode, err := cfg.modePolicy.apply(path, fi.Mode())
+	if err != nil {
+		return err
 	}
 
-	return installStream(fs, filepath.Join(dst, p.Name), gzr, fi.Mode())
+	cr := &countingReader{Reader: gzr}
+
+	if err := installStream(cfg.destFs, path, cr, mode); err != nil {
+		return err
+	}
+
+	cfg.log(ctx).Debug(ctx, "extracted file", "entry", p.Name, "path", path, "mode", mode)
+	cfg.observer.ObserveEntry(ctx, p.Name, src.n, cr.n)
+
+	return nil
 }
 
-func extractTar(fs afero.Fs, dst, pluginDir string, tr *tar.Reader) error {
+// extractTar extracts the plugin files, src is the archive that the tar reader reads from. As the archive is read
+// ahead, the bytes read for an entry are the ones read since the previous entry.
+func extractTar(ctx context.Context, cfg *config, dst, pluginDir string, tr *tar.Reader, src *countingReader) error {
+	var read int64
+
+	extracted := entries{}
+
 	for {
 		header, err := tr.Next()
 
@@ -106,22 +126,48 @@ func extractTar(fs afero.Fs, dst, pluginDir string, tr *tar.Reader) error {
 			continue
 		}
 
-		path := filepath.Join(dst, strings.TrimPrefix(header.Name, pluginDir))
+		name, ok := cfg.entryPath(pluginDir, header.Name)
+		if !ok || cfg.filter.skip(name, header.Typeflag == tar.TypeDir) {
+			continue
+		}
+
+		path := filepath.Join(dst, name)
 
 		if !strings.HasPrefix(path, dst) {
-			return fmt.Errorf("%s: %w", path, ErrIllegalFilePath)
+			return entryError(header.Name, fmt.Errorf("%s: %w", path, ErrIllegalFilePath))
+		}
+
+		mode, err := cfg.modePolicy.apply(path, header.FileInfo().Mode())
+		if err != nil {
+			return entryError(header.Name, err)
 		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if err := createPathIfNotExists(fs, path); err != nil {
-				return err
+			if err := createPathIfNotExists(cfg.destFs, path, mode); err != nil {
+				return entryError(header.Name, err)
 			}
 
 		case tar.TypeReg:
-			if err = installStream(fs, path, tr, os.FileMode(header.Mode)); err != nil { //nolint: gosec
-				return err
+			skip, err := extracted.skip(cfg.duplicatePolicy, path)
+			if err != nil {
+				return entryError(header.Name, err)
 			}
+
+			if skip {
+				continue
+			}
+
+			cr := &countingReader{Reader: tr}
+
+			if err = installStream(cfg.destFs, path, cr, mode); err != nil {
+				return entryError(header.Name, err)
+			}
+
+			cfg.log(ctx).Debug(ctx, "extracted file", "entry", header.Name, "path", path, "mode", mode)
+			cfg.observer.ObserveEntry(ctx, header.Name, src.n-read, cr.n)
+
+			read = src.n
 		}
 	}
 }
@@ -315,6 +315,14 @@ func TestGZipInstaller_Install_Error(t *testing.T) {
 				fs.On("Open", "/tmp/.plugin.registry.yaml").
 					Return(f, nil)
 
+				expectInstallLock(fs, "/app/plugins", "my-plugin")
+
+				fs.On("Stat", "/app/plugins/my-plugin").
+					Return(aferomock.NopFileInfo(t), nil)
+
+				fs.On("RemoveAll", "/app/plugins/.my-plugin.staging").
+					Return(nil)
+
 				fs.On("Open", "/tmp/my-plugin.tar.gz").
 					Return(nil, errors.New("could not open gzip file"))
 			}),
@@ -503,9 +511,9 @@ func TestInstallGzip_Error(t *testing.T) {
 
 			dest := t.TempDir()
 
-			fs := tc.mockFs(t)
+			cfg := newConfig(tc.mockFs(t))
 			p := plugin.Plugin{Name: "my-plugin"}
-			err := installGzip(fs, dest, p, tc.path)
+			err := installGzip(context.Background(), &cfg, dest, p, tc.path)
 
 			if tc.expectedError == "" {
 				require.NoError(t, err)
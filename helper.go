@@ -43,9 +43,9 @@ func openPluginFile(fs afero.Fs, path string) (os.FileInfo, afero.File, error) {
 	return fi, f, err
 }
 
-func createPathIfNotExists(fs afero.Fs, path string) error {
+func createPathIfNotExists(fs afero.Fs, path string, mode os.FileMode) error {
 	if _, err := fs.Stat(path); err != nil {
-		if err := fs.MkdirAll(path, 0o755); err != nil {
+		if err := fs.MkdirAll(path, mode); err != nil {
 			return err
 		}
 	}
@@ -1,12 +1,18 @@
 package fs
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"io"
 	"os"
 	"strings"
 	"testing"
+	"testing/iotest"
 
+	"github.com/nhatthm/plugin-registry/installer"
 	"github.com/spf13/afero"
 	"github.com/spf13/afero/mem"
 	"github.com/stretchr/testify/assert"
@@ -14,6 +20,21 @@ import (
 	"go.nhat.io/aferomock"
 )
 
+// newInstaller creates the installer with the name, from the detectors table.
+func newInstaller(t *testing.T, name string, fs afero.Fs, opts ...Option) installer.Installer {
+	t.Helper()
+
+	for _, d := range detectors {
+		if d.name == name {
+			return d.new(fs, opts...)
+		}
+	}
+
+	t.Fatalf("unknown installer %q", name)
+
+	return nil
+}
+
 func newEmptyFile(name string) *mem.File {
 	return mem.NewFileHandle(mem.CreateFile(name))
 }
@@ -32,6 +53,72 @@ func newShadowedFile(name string, source string) *mem.File {
 	return f
 }
 
+type archiveEntry struct {
+	name    string
+	content string
+	mode    int64
+}
+
+func (e archiveEntry) fileMode() os.FileMode {
+	if e.mode == 0 {
+		return 0o755
+	}
+
+	return os.FileMode(e.mode & 0o777)
+}
+
+func newTarGz(t *testing.T, files ...archiveEntry) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+
+	for _, f := range files {
+		mode := f.mode
+		if mode == 0 {
+			mode = 0o755
+		}
+
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f.name,
+			Mode:     mode,
+			Size:     int64(len(f.content)),
+		}))
+
+		_, err := tw.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	return buf.Bytes()
+}
+
+func newZip(t *testing.T, files ...archiveEntry) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+
+	for _, f := range files {
+		h := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+		h.SetMode(f.fileMode())
+
+		w, err := zw.CreateHeader(h)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
 func TestMetadataError(t *testing.T) {
 	t.Parallel()
 
@@ -84,7 +171,7 @@ func TestCreatePathIfNotExists(t *testing.T) {
 		t.Run(tc.scenario, func(t *testing.T) {
 			t.Parallel()
 
-			err := createPathIfNotExists(tc.mockFs(t), "/tmp")
+			err := createPathIfNotExists(tc.mockFs(t), "/tmp", 0o755)
 
 			if tc.expectedError == "" {
 				require.NoError(t, err)
@@ -144,7 +231,7 @@ func TestInstallFile_OpenFail(t *testing.T) {
 
 	fs := aferomock.MockFs(func(fs *aferomock.Fs) {
 		//nolint: nosnakecase
-		fs.On("OpenFile", "/tmp/temp.txt", os.O_CREATE|os.O_RDWR, os.FileMode(0o755)).
+		fs.On("OpenFile", "/tmp/.temp.txt.tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0o755)).
 			Return(nil, errors.New("open error"))
 	})(t)
 
@@ -173,3 +260,60 @@ func TestInstallFile_Success(t *testing.T) {
 
 	assert.Equal(t, expected, content)
 }
+
+func TestInstallFile_ReplaceExisting(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "/tmp/temp.txt", []byte("hello world, this is a longer content"), 0o644))
+
+	err := installStream(fs, "/tmp/temp.txt", strings.NewReader("hello world"), os.FileMode(0o755))
+	require.NoError(t, err)
+
+	content, err := afero.ReadFile(fs, "/tmp/temp.txt")
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello world", string(content))
+
+	_, err = fs.Stat("/tmp/.temp.txt.tmp")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInstallFile_CopyFail(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	err := installStream(fs, "/tmp/temp.txt", iotest.ErrReader(errors.New("read error")), os.FileMode(0o755))
+	require.EqualError(t, err, "read error")
+
+	_, err = fs.Stat("/tmp/.temp.txt.tmp")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = fs.Stat("/tmp/temp.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInstallFile_RenameFail(t *testing.T) {
+	t.Parallel()
+
+	fs := aferomock.MockFs(func(fs *aferomock.Fs) {
+		fs.On("OpenFile", "/tmp/.temp.txt.tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0o755)).
+			Return(mem.NewFileHandle(mem.CreateFile("/tmp/.temp.txt.tmp")), nil)
+
+		fs.On("Rename", "/tmp/.temp.txt.tmp", "/tmp/temp.txt").
+			Return(errors.New("rename error"))
+
+		fs.On("Remove", "/tmp/.temp.txt.tmp").
+			Return(nil)
+	})(t)
+
+	err := installStream(fs, "/tmp/temp.txt", strings.NewReader("hello"), os.FileMode(0o755))
+	require.EqualError(t, err, "rename error")
+
+	var ie *InstallError
+
+	require.True(t, errors.As(err, &ie))
+	assert.Equal(t, PhaseFinalize, ie.Phase)
+}
@@ -0,0 +1,241 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+)
+
+// ErrPluginNotInstalled indicates that the plugin is not installed in the destination.
+var ErrPluginNotInstalled = errors.New("plugin is not installed")
+
+// defaultHookTimeout is the timeout of a hook that has no timeout.
+const defaultHookTimeout = time.Minute
+
+// HookEvent is an event of the plugin lifecycle that runs hooks.
+type HookEvent string
+
+const (
+	// PreInstall runs before the plugin is installed, in the destination directory.
+	PreInstall HookEvent = "pre-install"
+	// PostInstall runs after the plugin is installed and validated, in the plugin directory. The previously installed
+	// version is restored if a hook fails.
+	PostInstall HookEvent = "post-install"
+	// PreUninstall runs before the plugin is uninstalled, in the plugin directory.
+	PreUninstall HookEvent = "pre-uninstall"
+)
+
+// Hook is a command that runs on an event of the plugin lifecycle. A relative command with a path separator, such as
+// ./my-plugin, is relative to the directory in which the hook runs.
+type Hook struct {
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Hooks are the hooks of a plugin, declared in .plugin.registry.yaml:
+//
+//	hooks:
+//	  post-install:
+//	    - command: ./my-plugin
+//	      args: [init]
+//	      timeout: 10s
+type Hooks struct {
+	PreInstall   []Hook `yaml:"pre-install,omitempty"`
+	PostInstall  []Hook `yaml:"post-install,omitempty"`
+	PreUninstall []Hook `yaml:"pre-uninstall,omitempty"`
+}
+
+// HookEnv is the environment of a hook.
+type HookEnv struct {
+	Event   HookEvent
+	Plugin  string
+	Version string
+	// Dir is the directory of the plugin, which does not exist yet on PreInstall.
+	Dir string
+	// WorkDir is the directory in which the hook runs.
+	WorkDir string
+}
+
+// HookRunner runs the hooks.
+type HookRunner interface {
+	// RunHook runs the hook and returns its output.
+	RunHook(ctx context.Context, h Hook, env HookEnv) ([]byte, error)
+}
+
+// HookError indicates that a hook fails.
+type HookError struct {
+	Event   HookEvent
+	Command string
+	Output  []byte
+	Err     error
+}
+
+// Error satisfies the error interface.
+func (e *HookError) Error() string {
+	msg := fmt.Sprintf("%s hook %q failed: %s", e.Event, e.Command, e.Err)
+
+	if out := strings.TrimSpace(string(e.Output)); out != "" {
+		msg += ": " + out
+	}
+
+	return msg
+}
+
+// Unwrap returns the cause of the error.
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// WithHookRunner enables the hooks of the plugins and sets their runner. Without it, the hooks are ignored.
+func WithHookRunner(r HookRunner) Option {
+	return func(c *config) {
+		c.hookRunner = r
+	}
+}
+
+// ExecHookRunner runs the hooks as processes. A hook gets the PLUGIN_HOOK, PLUGIN_NAME, PLUGIN_VERSION and PLUGIN_DIR
+// environment variables, in addition to Env, or only PATH if Env is nil. The standard output and error are captured.
+type ExecHookRunner struct {
+	// Env is the base environment of the hooks, in the form "key=value".
+	Env []string
+	// Timeout is the timeout of the hooks that have no timeout, one minute by default.
+	Timeout time.Duration
+}
+
+var _ HookRunner = ExecHookRunner{}
+
+// RunHook runs the hook.
+func (r ExecHookRunner) RunHook(ctx context.Context, h Hook, env HookEnv) ([]byte, error) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = r.Timeout
+	}
+
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var out bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, h.Command, h.Args...) //nolint: gosec
+	cmd.Dir = env.WorkDir
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	cmd.Env = r.Env
+	if cmd.Env == nil {
+		cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+	}
+
+	cmd.Env = append(cmd.Env,
+		"PLUGIN_HOOK="+string(env.Event),
+		"PLUGIN_NAME="+env.Plugin,
+		"PLUGIN_VERSION="+env.Version,
+		"PLUGIN_DIR="+env.Dir,
+	)
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	return out.Bytes(), err
+}
+
+// runHooks runs the hooks of the event, it stops at the first failure.
+func (c *config) runHooks(ctx context.Context, event HookEvent, hooks []Hook, dest string, p *plugin.Plugin) error {
+	if c.hookRunner == nil {
+		return nil
+	}
+
+	env := HookEnv{
+		Event:   event,
+		Plugin:  p.Name,
+		Version: p.Version,
+		Dir:     filepath.Join(dest, p.Name),
+		WorkDir: filepath.Join(dest, p.Name),
+	}
+
+	if event == PreInstall {
+		env.WorkDir = dest
+	}
+
+	for _, h := range hooks {
+		out, err := c.hookRunner.RunHook(ctx, h, env)
+
+		c.log(ctx).Debug(ctx, "ran hook", "event", event, "plugin", p.Name, "command", h.Command, "output", string(out))
+
+		if err != nil {
+			return &HookError{Event: event, Command: h.Command, Output: out, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Uninstall removes the plugin from the destination, after running its pre-uninstall hooks if the installer has a
+// hook runner. The plugin is kept if a hook fails. The hooks are skipped when the plugin is a dangling link.
+func (c *config) Uninstall(ctx context.Context, dest, name string) error {
+	path := filepath.Join(dest, name)
+
+	installed, err := lexists(c.destFs, path)
+	if err != nil {
+		return err
+	}
+
+	if !installed {
+		return fmt.Errorf("%s: %w", name, ErrPluginNotInstalled)
+	}
+
+	l, err := c.lock(ctx, dest, name)
+	if err != nil {
+		return err
+	}
+
+	defer l.release() //nolint: errcheck
+
+	r, err := readRecord(c.destFs, dest, name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	p := &plugin.Plugin{Name: name, Version: r.Version}
+
+	// The hooks of a linked plugin whose source has been removed are gone with it.
+	switch err := checkPluginLink(c.destFs, dest, name); {
+	case errors.Is(err, ErrDanglingLink):
+		c.log(ctx).Warn(ctx, "skipping pre-uninstall hooks", "plugin", name, "dest", dest, "error", err)
+
+	case err != nil:
+		return err
+
+	default:
+		if err := c.runHooks(ctx, PreUninstall, r.Hooks.PreUninstall, dest, p); err != nil {
+			return err
+		}
+	}
+
+	if err := c.destFs.RemoveAll(path); err != nil {
+		return err
+	}
+
+	c.log(ctx).Info(ctx, "plugin uninstalled", "plugin", name, "dest", dest)
+
+	if err := c.destFs.Remove(recordPath(dest, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
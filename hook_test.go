@@ -0,0 +1,211 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hookCall struct {
+	event   HookEvent
+	command string
+	workDir string
+}
+
+type hookRunnerStub struct {
+	mu    sync.Mutex
+	calls []hookCall
+	fail  HookEvent
+}
+
+func (r *hookRunnerStub) RunHook(_ context.Context, h Hook, env HookEnv) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, hookCall{event: env.Event, command: h.Command, workDir: env.WorkDir})
+
+	if env.Event == r.fail {
+		return []byte("something went wrong\n"), errors.New("exit status 1")
+	}
+
+	return nil, nil
+}
+
+const hooksMetadata = `name: my-plugin
+version: 1.0.0
+hooks:
+  pre-install:
+    - command: pre
+  post-install:
+    - command: ./my-plugin
+      args: [init]
+  pre-uninstall:
+    - command: ./my-plugin
+      args: [cleanup]
+`
+
+func newHooksFs(t *testing.T) afero.Fs {
+	t.Helper()
+
+	return newMultiPluginFs(t, map[string]string{
+		"/src/.plugin.registry.yaml": hooksMetadata,
+		"/src/my-plugin/my-plugin":   "#!/bin/bash\n",
+	})
+}
+
+func TestInstaller_Hooks(t *testing.T) {
+	t.Parallel()
+
+	fs := newHooksFs(t)
+	r := &hookRunnerStub{}
+	i := NewFsInstaller(fs, WithHookRunner(r))
+
+	_, err := i.Install(context.Background(), "/app/plugins", "/src")
+	require.NoError(t, err)
+
+	expected := []hookCall{
+		{event: PreInstall, command: "pre", workDir: "/app/plugins"},
+		{event: PostInstall, command: "./my-plugin", workDir: "/app/plugins/my-plugin"},
+	}
+
+	assert.Equal(t, expected, r.calls)
+
+	// The post-install hooks see the plugin at its final path.
+	isDir, err := afero.IsDir(fs, r.calls[1].workDir)
+	require.NoError(t, err)
+	assert.True(t, isDir)
+
+	err = i.Uninstall(context.Background(), "/app/plugins", "my-plugin")
+	require.NoError(t, err)
+
+	expected = append(expected, hookCall{event: PreUninstall, command: "./my-plugin", workDir: "/app/plugins/my-plugin"})
+
+	assert.Equal(t, expected, r.calls)
+
+	for _, path := range []string{"/app/plugins/my-plugin", "/app/plugins/.my-plugin.install.yaml"} {
+		_, err = fs.Stat(path)
+		assert.True(t, os.IsNotExist(err), path)
+	}
+}
+
+func TestInstaller_Hooks_Fail(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		event         HookEvent
+		expectedError string
+	}{
+		{
+			scenario:      "pre-install",
+			event:         PreInstall,
+			expectedError: `could not run hook: pre-install hook "pre" failed: exit status 1: something went wrong`,
+		},
+		{
+			scenario:      "post-install",
+			event:         PostInstall,
+			expectedError: `could not run hook: post-install hook "./my-plugin" failed: exit status 1: something went wrong`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := newHooksFs(t)
+			r := &hookRunnerStub{fail: tc.event}
+
+			p, err := NewFsInstaller(fs, WithHookRunner(r)).Install(context.Background(), "/app/plugins", "/src")
+
+			assert.Nil(t, p)
+			require.EqualError(t, err, tc.expectedError)
+
+			var hookErr *HookError
+
+			require.True(t, errors.As(err, &hookErr))
+			assert.Equal(t, tc.event, hookErr.Event)
+			assert.Equal(t, "something went wrong\n", string(hookErr.Output))
+			assert.Equal(t, "hook", ErrorKind(err))
+
+			_, err = fs.Stat("/app/plugins/my-plugin")
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestInstaller_Uninstall(t *testing.T) {
+	t.Parallel()
+
+	fs := newHooksFs(t)
+	r := &hookRunnerStub{fail: PreUninstall}
+	i := NewFsInstaller(fs, WithHookRunner(r))
+
+	err := i.Uninstall(context.Background(), "/app/plugins", "my-plugin")
+	require.ErrorIs(t, err, ErrPluginNotInstalled)
+
+	_, err = i.Install(context.Background(), "/app/plugins", "/src")
+	require.NoError(t, err)
+
+	err = i.Uninstall(context.Background(), "/app/plugins", "my-plugin")
+	require.EqualError(t, err, `pre-uninstall hook "./my-plugin" failed: exit status 1: something went wrong`)
+
+	_, err = fs.Stat("/app/plugins/my-plugin/my-plugin")
+	assert.NoError(t, err)
+}
+
+func TestInstaller_Hooks_NoRunner(t *testing.T) {
+	t.Parallel()
+
+	fs := newHooksFs(t)
+
+	_, err := NewFsInstaller(fs).Install(context.Background(), "/app/plugins", "/src")
+	require.NoError(t, err)
+
+	r, err := readRecord(fs, "/app/plugins", "my-plugin")
+	require.NoError(t, err)
+
+	assert.Empty(t, r.Hooks.PreUninstall)
+}
+
+func TestExecHookRunner(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run with sh")
+	}
+
+	dir := t.TempDir()
+	env := HookEnv{Event: PostInstall, Plugin: "my-plugin", Version: "1.0.0", Dir: "/app/plugins/my-plugin", WorkDir: dir}
+	script := "echo $PLUGIN_HOOK $PLUGIN_NAME $PLUGIN_VERSION $PLUGIN_DIR $HOME; pwd; echo oops >&2"
+
+	out, err := ExecHookRunner{}.RunHook(context.Background(), Hook{Command: "sh", Args: []string{"-c", script}}, env)
+	require.NoError(t, err)
+
+	realDir, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, "post-install my-plugin 1.0.0 /app/plugins/my-plugin\n"+realDir+"\noops\n", string(out))
+}
+
+func TestExecHookRunner_Timeout(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run with sh")
+	}
+
+	h := Hook{Command: "sleep", Args: []string{"5"}, Timeout: 50 * time.Millisecond}
+
+	_, err := ExecHookRunner{}.RunHook(context.Background(), h, HookEnv{WorkDir: t.TempDir()})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
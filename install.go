@@ -0,0 +1,191 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+// installFunc copies or extracts the plugin to <dest>/<name>.
+type installFunc func(ctx context.Context, cfg *config, dest string) error
+
+// run locks the destination and installs the plugin to a staging folder, which then replaces the installed plugin. The
+// plugin is validated and its post-install hooks run at its final path, and the installed plugin is restored if any of
+// them fails. The source is the path given to the installer, and the path is the path of the plugin folder or archive.
+func (c *config) run(ctx context.Context, dest, source, path string, p *plugin.Plugin, install installFunc) (err error) {
+	logger := c.log(ctx)
+	event := InstallEvent{Plugin: p, Dest: dest, Source: source}
+
+	c.events.emit(ctx, &c.events.before, event)
+
+	defer func() {
+		if err != nil {
+			event.Err = err
+
+			c.events.emit(ctx, &c.events.failed, event)
+
+			return
+		}
+
+		c.events.emit(ctx, &c.events.after, event)
+	}()
+
+	l, err := c.lock(ctx, dest, p.Name)
+	if err != nil {
+		return installError(ctx, PhaseLock, source, err, "could not lock plugin", "path", path)
+	}
+
+	defer l.release() //nolint: errcheck
+
+	if err := c.runHooks(ctx, PreInstall, c.hooks.PreInstall, dest, p); err != nil {
+		return installError(ctx, PhaseHook, source, err, "could not run hook", "path", path)
+	}
+
+	extractCtx, end := c.startPhase(ctx, PhaseExtract)
+	start := time.Now()
+
+	logger.Info(extractCtx, "installing plugin", "plugin", p.Name, "path", path, "dest", dest)
+
+	staging := stagingPath(dest, p.Name)
+
+	err = c.destFs.RemoveAll(staging)
+	if err == nil {
+		err = install(extractCtx, c, staging)
+	}
+
+	end(err)
+
+	if err != nil {
+		err = installError(ctx, PhaseExtract, source, err, "could not install plugin", "path", path)
+
+		rollback(c.destFs, dest, p)
+
+		logger.Error(ctx, "could not install plugin", "plugin", p.Name, "path", path, "error", err)
+
+		return err
+	}
+
+	logger.Info(extractCtx, "plugin installed", "plugin", p.Name, "dest", dest, "elapsed", time.Since(start))
+
+	restore, err := c.replace(dest, p)
+	if err != nil {
+		rollback(c.destFs, dest, p)
+
+		return installError(ctx, PhaseFinalize, source, err, "could not replace plugin", "path", path)
+	}
+
+	verifyCtx, end := c.startPhase(ctx, PhaseVerify)
+	err = c.validate(dest, p)
+
+	end(err)
+
+	if err != nil {
+		err = installError(ctx, PhaseVerify, source, err, "could not validate plugin", "path", path)
+
+		logger.Warn(verifyCtx, "rolling back plugin", "plugin", p.Name, "dest", dest, "error", err)
+		restore()
+
+		return err
+	}
+
+	logger.Debug(verifyCtx, "plugin validated", "plugin", p.Name, "dest", dest)
+
+	if err := c.runHooks(ctx, PostInstall, c.hooks.PostInstall, dest, p); err != nil {
+		err = installError(ctx, PhaseHook, source, err, "could not run hook", "path", path)
+
+		logger.Warn(ctx, "rolling back plugin", "plugin", p.Name, "dest", dest, "error", err)
+		restore()
+
+		return err
+	}
+
+	if err := c.saveRecord(dest, source, p); err != nil {
+		restore()
+
+		return installError(ctx, PhaseFinalize, source, err, "could not save install record", "path", path)
+	}
+
+	_ = c.destFs.RemoveAll(backupPath(dest, p.Name)) //nolint: errcheck
+
+	return nil
+}
+
+// stagingPath returns the path of the folder in which the plugin is installed before it replaces the installed one.
+func stagingPath(dest, name string) string {
+	return filepath.Join(dest, fmt.Sprintf(".%s.staging", name))
+}
+
+// backupPath returns the path to which the installed plugin is moved while the staged plugin replaces it.
+func backupPath(dest, name string) string {
+	return filepath.Join(dest, fmt.Sprintf(".%s.backup", name))
+}
+
+// replace moves the staged plugin to <dest>/<name>. The installed plugin is moved aside to the backup path, the
+// returned function moves it back, and the backup is removed once the new plugin is validated and recorded. The
+// install record is only written after that, so it always matches the plugin folder.
+func (c *config) replace(dest string, p *plugin.Plugin) (func(), error) {
+	path := filepath.Join(dest, p.Name)
+	backup := backupPath(dest, p.Name)
+	staging := stagingPath(dest, p.Name)
+
+	if err := c.destFs.RemoveAll(backup); err != nil {
+		return nil, err
+	}
+
+	installed, err := lexists(c.destFs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if installed {
+		if err := c.destFs.Rename(path, backup); err != nil {
+			return nil, err
+		}
+	}
+
+	restore := func() {
+		_ = c.destFs.RemoveAll(path) //nolint: errcheck
+
+		if installed {
+			_ = c.destFs.Rename(backup, path) //nolint: errcheck
+		}
+
+		rollback(c.destFs, dest, p)
+	}
+
+	if err := c.destFs.Rename(filepath.Join(staging, p.Name), path); err != nil {
+		restore()
+
+		return nil, err
+	}
+
+	_ = c.destFs.RemoveAll(staging) //nolint: errcheck
+
+	return restore, nil
+}
+
+// lexists checks whether the path exists, without following the symlinks.
+func lexists(fs afero.Fs, path string) (bool, error) {
+	var err error
+
+	if l, ok := fs.(afero.Lstater); ok {
+		_, _, err = l.LstatIfPossible(path)
+	} else {
+		_, err = fs.Stat(path)
+	}
+
+	if err == nil {
+		return true, nil
+	}
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return false, err
+}
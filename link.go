@@ -0,0 +1,122 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+var (
+	// ErrLinkNotSupported indicates that the destination file system does not support symlinks.
+	ErrLinkNotSupported = errors.New("file system does not support symlinks, could not install plugin in link mode")
+	// ErrLinkAcrossFs indicates that the source and the destination are different file systems, the link could not
+	// point to the source.
+	ErrLinkAcrossFs = errors.New("source and destination file systems are different, could not install plugin in link mode")
+	// ErrDanglingLink indicates that the link points to a path that does not exist.
+	ErrDanglingLink = errors.New("dangling link")
+)
+
+// WithLinkMode makes the filesystem installer symlink the plugin to its source folder instead of copying it, so
+// rebuilding the plugin does not require reinstalling it. The destination file system must support symlinks, and be
+// the source file system.
+func WithLinkMode() Option {
+	return func(c *config) {
+		c.link = true
+	}
+}
+
+func installLink(_ context.Context, cfg *config, dest, src string, p *plugin.Plugin) error {
+	fs, ok := cfg.destFs.(afero.Symlinker)
+	if !ok {
+		return ErrLinkNotSupported
+	}
+
+	if cfg.srcFs != cfg.destFs {
+		return ErrLinkAcrossFs
+	}
+
+	src, err := filepath.Abs(filepath.Join(src, p.Name))
+	if err != nil {
+		return err
+	}
+
+	dest = filepath.Join(dest, p.Name)
+
+	if err := cfg.destFs.RemoveAll(dest); err != nil {
+		return err
+	}
+
+	if isDir, _ := afero.IsDir(cfg.srcFs, src); !isDir { //nolint: errcheck
+		dest = filepath.Join(dest, p.Name)
+	}
+
+	if err := cfg.destFs.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	if err := fs.SymlinkIfPossible(src, dest); err != nil {
+		return err
+	}
+
+	if err := checkLink(cfg.destFs, dest); err != nil {
+		_ = cfg.destFs.Remove(dest) //nolint: errcheck
+
+		return err
+	}
+
+	return nil
+}
+
+// checkLink checks whether the link at the path resolves to an existing file or directory.
+func checkLink(fs afero.Fs, path string) error {
+	lr, ok := fs.(afero.LinkReader)
+	if !ok {
+		return ErrLinkNotSupported
+	}
+
+	target, err := lr.ReadlinkIfPossible(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fs.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s -> %s: %w", path, target, ErrDanglingLink)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// checkPluginLink checks the link of a plugin installed in link mode, which is either the plugin folder or, for a single
+// binary, the binary in it. The plugins that are not linked are not checked.
+func checkPluginLink(fs afero.Fs, dest, name string) error {
+	path := filepath.Join(dest, name)
+
+	for _, p := range []string{path, filepath.Join(path, name)} {
+		if isSymlink(fs, p) {
+			return checkLink(fs, p)
+		}
+	}
+
+	return nil
+}
+
+// isSymlink checks whether the path is a symlink, without following it.
+func isSymlink(fs afero.Fs, path string) bool {
+	l, ok := fs.(afero.Lstater)
+	if !ok {
+		return false
+	}
+
+	fi, _, err := l.LstatIfPossible(path)
+
+	return err == nil && fi.Mode()&os.ModeSymlink != 0
+}
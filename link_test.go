@@ -0,0 +1,167 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/aferocopy/v2"
+)
+
+func TestFsInstaller_Install_LinkNotSupported(t *testing.T) {
+	t.Parallel()
+
+	i := NewFsInstaller(afero.NewOsFs(), WithLinkMode(), WithDestinationFs(afero.NewMemMapFs()))
+
+	result, err := i.Install(context.Background(), "/app/plugins", "resources/fixtures/fs/folder")
+
+	expected := `could not install plugin: file system does not support symlinks, could not install plugin in link mode`
+
+	assert.Nil(t, result)
+	require.EqualError(t, err, expected)
+}
+
+func TestFsInstaller_Install_LinkAcrossFs(t *testing.T) {
+	t.Parallel()
+
+	i := NewFsInstaller(afero.NewOsFs(), WithLinkMode(), WithDestinationFs(afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())))
+
+	result, err := i.Install(context.Background(), "/app/plugins", "resources/fixtures/fs/folder")
+
+	expected := `could not install plugin: source and destination file systems are different, could not install plugin in link mode`
+
+	assert.Nil(t, result)
+	require.EqualError(t, err, expected)
+}
+
+func TestFsInstaller_Install_Link(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario     string
+		path         string
+		expectedLink string
+	}{
+		{
+			scenario:     "folder",
+			path:         "resources/fixtures/fs/folder",
+			expectedLink: "my-plugin",
+		},
+		{
+			scenario:     "file",
+			path:         "resources/fixtures/fs/file",
+			expectedLink: "my-plugin/my-plugin",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			dest := t.TempDir()
+			osFs := afero.NewOsFs()
+			i := NewFsInstaller(osFs, WithLinkMode())
+
+			// Install twice to make sure the existing link is replaced.
+			for n := 0; n < 2; n++ {
+				_, err := i.Install(context.Background(), dest, tc.path)
+				require.NoError(t, err)
+			}
+
+			expectedTarget, err := filepath.Abs(filepath.Join(tc.path, "my-plugin"))
+			require.NoError(t, err)
+
+			target, err := os.Readlink(filepath.Join(dest, tc.expectedLink))
+			require.NoError(t, err)
+
+			assert.Equal(t, expectedTarget, target)
+
+			data, err := afero.ReadFile(osFs, filepath.Join(dest, "my-plugin", "my-plugin"))
+			require.NoError(t, err)
+
+			assert.Equal(t, "#!/bin/bash\n", string(data))
+		})
+	}
+}
+
+func TestInstaller_Uninstall_DanglingLink(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		path     string
+	}{
+		{
+			scenario: "folder",
+			path:     "resources/fixtures/fs/folder",
+		},
+		{
+			scenario: "file",
+			path:     "resources/fixtures/fs/file",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			osFs := afero.NewOsFs()
+			src := filepath.Join(t.TempDir(), "src")
+			dest := t.TempDir()
+
+			require.NoError(t, aferocopy.Copy(tc.path, src))
+			require.NoError(t, afero.WriteFile(osFs, filepath.Join(src, ".plugin.registry.yaml"), []byte(hooksMetadata), 0o644))
+
+			r := &hookRunnerStub{}
+			i := NewFsInstaller(osFs, WithLinkMode(), WithHookRunner(r))
+
+			_, err := i.Install(context.Background(), dest, src)
+			require.NoError(t, err)
+
+			require.NoError(t, os.RemoveAll(src))
+
+			plugins, err := i.List(context.Background(), dest)
+			require.NoError(t, err)
+			require.Len(t, plugins, 1)
+
+			assert.Equal(t, StatusIncomplete, plugins[0].Status)
+			assert.ErrorIs(t, plugins[0].Problem, ErrDanglingLink)
+
+			require.NoError(t, i.Uninstall(context.Background(), dest, "my-plugin"))
+
+			plugins, err = i.List(context.Background(), dest)
+			require.NoError(t, err)
+
+			assert.Empty(t, plugins)
+
+			for _, c := range r.calls {
+				assert.NotEqual(t, PreUninstall, c.event)
+			}
+		})
+	}
+}
+
+func TestCheckLink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "link")
+
+	require.NoError(t, os.Symlink(target, link))
+
+	err := checkLink(afero.NewOsFs(), link)
+	require.ErrorIs(t, err, ErrDanglingLink)
+
+	require.NoError(t, os.Mkdir(target, 0o755))
+	require.NoError(t, checkLink(afero.NewOsFs(), link))
+
+	err = checkLink(afero.NewMemMapFs(), link)
+	require.ErrorIs(t, err, ErrLinkNotSupported)
+}
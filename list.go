@@ -0,0 +1,268 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	recordSuffix  = ".install.yaml"
+	lockSuffix    = ".lock"
+	stagingSuffix = ".staging"
+	backupSuffix  = ".backup"
+	tmpSuffix     = ".tmp"
+)
+
+var (
+	// ErrNoInstallRecord indicates that a plugin folder was not installed by the installers, or by an older version
+	// that did not record the installations.
+	ErrNoInstallRecord = errors.New("plugin has no install record")
+	// ErrInterruptedInstall indicates that an installation left its staging or backup folder behind, installing the
+	// plugin again removes it.
+	ErrInterruptedInstall = errors.New("installation was interrupted")
+)
+
+// InstallStatus is the status of a plugin in the destination.
+type InstallStatus string
+
+const (
+	// StatusInstalled means that the plugin is completely installed.
+	StatusInstalled InstallStatus = "installed"
+	// StatusIncomplete means that the installation of the plugin is in progress, or has been interrupted.
+	StatusIncomplete InstallStatus = "incomplete"
+	// StatusForeign means that the plugin folder was not installed by the installers.
+	StatusForeign InstallStatus = "foreign"
+)
+
+// InstalledPlugin is a plugin in the destination.
+type InstalledPlugin struct {
+	Name string
+	Path string
+
+	// Version, Installer, Source and InstalledAt are read from the install record, they are empty for the foreign
+	// plugins.
+	Version     string
+	Installer   string
+	Source      string
+	InstalledAt time.Time
+
+	Status InstallStatus
+	// Problem tells why the plugin is incomplete or foreign.
+	Problem error
+}
+
+// installRecord is kept next to the plugin folder, at dest/.<name>.install.yaml, when the plugin is installed.
+type installRecord struct {
+	Version     string    `yaml:"version,omitempty"`
+	Installer   string    `yaml:"installer"`
+	Source      string    `yaml:"source"`
+	InstalledAt time.Time `yaml:"installed_at"`
+	// Hooks are the pre-uninstall hooks, for Uninstall.
+	Hooks Hooks `yaml:"hooks,omitempty"`
+}
+
+// recordPath returns the path of the install record of the plugin, next to the plugin folder.
+func recordPath(dest, name string) string {
+	return filepath.Join(dest, "."+name+recordSuffix)
+}
+
+// saveRecord records the installation of the plugin, with its pre-uninstall hooks if the installer has a hook runner.
+// The record is written to a temporary file first, so the previous record is kept when it could not be written.
+func (c *config) saveRecord(dest, source string, p *plugin.Plugin) error {
+	r := installRecord{
+		Version:     p.Version,
+		Installer:   c.kind,
+		Source:      source,
+		InstalledAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	if c.hookRunner != nil {
+		r.Hooks.PreUninstall = c.hooks.PreUninstall
+	}
+
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	path := recordPath(dest, p.Name)
+	tmp := path + tmpSuffix
+
+	if err := afero.WriteFile(c.destFs, tmp, data, 0o644); err != nil {
+		_ = c.destFs.Remove(tmp) //nolint: errcheck
+
+		return err
+	}
+
+	if err := c.destFs.Rename(tmp, path); err != nil {
+		_ = c.destFs.Remove(tmp) //nolint: errcheck
+
+		return err
+	}
+
+	return nil
+}
+
+func readRecord(fs afero.Fs, dest, name string) (installRecord, error) {
+	var r installRecord
+
+	data, err := afero.ReadFile(fs, recordPath(dest, name))
+	if err != nil {
+		return r, err
+	}
+
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("%s: %w", recordPath(dest, name), err)
+	}
+
+	return r, nil
+}
+
+// List lists the plugins in the destination, sorted by name. The plugins that are being installed, or whose
+// installation has been interrupted, are StatusIncomplete, and the folders that have no install record are
+// StatusForeign. The hidden files are ignored, except the staging and backup folders of the installations.
+func (c *config) List(ctx context.Context, dest string) ([]InstalledPlugin, error) {
+	files, err := afero.ReadDir(c.destFs, dest)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	names := make(map[string]struct{})
+	records := make(map[string]struct{})
+	locks := make(map[string]struct{})
+	leftovers := make(map[string]string)
+
+	for _, f := range files {
+		name := f.Name()
+
+		switch {
+		case !strings.HasPrefix(name, "."):
+			names[name] = struct{}{}
+
+		case strings.HasSuffix(name, recordSuffix) && !f.IsDir():
+			name = strings.TrimSuffix(strings.TrimPrefix(name, "."), recordSuffix)
+			names[name] = struct{}{}
+			records[name] = struct{}{}
+
+		case strings.HasSuffix(name, lockSuffix) && !f.IsDir():
+			locks[strings.TrimSuffix(strings.TrimPrefix(name, "."), lockSuffix)] = struct{}{}
+
+		case strings.HasSuffix(name, stagingSuffix) && f.IsDir():
+			name = strings.TrimSuffix(strings.TrimPrefix(name, "."), stagingSuffix)
+			names[name] = struct{}{}
+			leftovers[name] = stagingPath(dest, name)
+
+		case strings.HasSuffix(name, backupSuffix) && f.IsDir():
+			name = strings.TrimSuffix(strings.TrimPrefix(name, "."), backupSuffix)
+			names[name] = struct{}{}
+			leftovers[name] = backupPath(dest, name)
+		}
+	}
+
+	result := make([]InstalledPlugin, 0, len(names))
+
+	for name := range names {
+		_, hasRecord := records[name]
+		_, hasLock := locks[name]
+
+		p, err := c.inspect(dest, name, hasRecord, hasLock, leftovers[name])
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	c.log(ctx).Debug(ctx, "plugins listed", "dest", dest, "count", len(result))
+
+	return result, nil
+}
+
+// inspect tells the status of a plugin in the destination, leftover is the staging or backup folder that an installation
+// left behind, if any.
+func (c *config) inspect(dest, name string, hasRecord, hasLock bool, leftover string) (InstalledPlugin, error) {
+	p := InstalledPlugin{
+		Name:   name,
+		Path:   filepath.Join(dest, name),
+		Status: StatusInstalled,
+	}
+
+	if hasRecord {
+		r, err := readRecord(c.destFs, dest, name)
+		if err != nil {
+			return p, err
+		}
+
+		p.Version, p.Installer, p.Source, p.InstalledAt = r.Version, r.Installer, r.Source, r.InstalledAt
+	}
+
+	if hasLock {
+		info, held, err := lockHeld(c.destFs, lockPath(dest, name))
+		if err != nil {
+			return p, err
+		}
+
+		if held {
+			p.Status, p.Problem = StatusIncomplete, &LockError{Path: lockPath(dest, name), PID: info.PID, Host: info.Host}
+
+			return p, nil
+		}
+	}
+
+	if leftover != "" {
+		p.Status, p.Problem = StatusIncomplete, fmt.Errorf("%s: %w", leftover, ErrInterruptedInstall)
+
+		return p, nil
+	}
+
+	installed, err := lexists(c.destFs, p.Path)
+	if err != nil {
+		return p, err
+	}
+
+	if !installed {
+		p.Status, p.Problem = StatusIncomplete, fmt.Errorf("%s: %w", name, ErrPluginNotInstalled)
+
+		return p, nil
+	}
+
+	if err := checkPluginLink(c.destFs, dest, name); err != nil {
+		if !errors.Is(err, ErrDanglingLink) {
+			return p, err
+		}
+
+		p.Status, p.Problem = StatusIncomplete, err
+
+		return p, nil
+	}
+
+	if !hasRecord {
+		p.Status, p.Problem = StatusForeign, fmt.Errorf("%s: %w", name, ErrNoInstallRecord)
+
+		return p, nil
+	}
+
+	if err := validateEntrypoint(c.destFs, dest, &plugin.Plugin{Name: name}); err != nil {
+		p.Status, p.Problem = StatusIncomplete, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,161 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstaller_List(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"/src/.plugin.registry.yaml":             "name: my-plugin\nversion: 1.2.0\n",
+		"/src/my-plugin/my-plugin":               "#!/bin/bash\n",
+		"/app/plugins/foreign/foreign":           "#!/bin/bash\n",
+		"/app/plugins/.cache/data":               "data",
+		"/app/plugins/.gone.install.yaml":        "version: 1.0.0\ninstaller: zip\nsource: /src/gone.zip\n",
+		"/app/plugins/.partial.install.yaml":     "installer: gzip\n",
+		"/app/plugins/partial/partial":           "#!/bin/bash\n",
+		"/app/plugins/.partial.staging/partial":  "#!/bin/bash\n",
+		"/app/plugins/.moving.install.yaml":      "installer: zip\n",
+		"/app/plugins/.moving.backup/moving":     "#!/bin/bash\n",
+		"/app/plugins/.stale.install.yaml":       "installer: fs\n",
+		"/app/plugins/stale/stale":               "#!/bin/bash\n",
+		"/app/plugins/.broken.install.yaml":      "installer: fs\n",
+		"/app/plugins/broken/README.md":          "broken",
+		"/app/plugins/.locked.install.yaml":      "installer: fs\n",
+		"/app/plugins/.locked.lock":              "pid: 42\nhost: other\n",
+		"/app/plugins/locked/locked":             "#!/bin/bash\n",
+		"/app/plugins/.unknown.lock":             "",
+		"/app/plugins/.unknown.install.yaml.bak": "",
+	}
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o755))
+	}
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	// The process that held the lock is dead.
+	writeLockFile(t, fs, "/app/plugins/.stale.lock", 1<<30, host)
+
+	i := NewFsInstaller(fs)
+	start := time.Now().UTC().Truncate(time.Second)
+
+	_, err = i.Install(context.Background(), "/app/plugins", "/src")
+	require.NoError(t, err)
+
+	// A released flock(2) lock file is kept empty.
+	require.NoError(t, afero.WriteFile(fs, "/app/plugins/.my-plugin.lock", nil, 0o644))
+
+	plugins, err := i.List(context.Background(), "/app/plugins")
+	require.NoError(t, err)
+
+	expected := []struct {
+		name          string
+		status        InstallStatus
+		installer     string
+		expectedError error
+	}{
+		{name: "broken", status: StatusIncomplete, installer: "fs", expectedError: ErrEntrypointMissing},
+		{name: "foreign", status: StatusForeign, expectedError: ErrNoInstallRecord},
+		{name: "gone", status: StatusIncomplete, installer: "zip", expectedError: ErrPluginNotInstalled},
+		{name: "locked", status: StatusIncomplete, installer: "fs", expectedError: ErrInstallLocked},
+		{name: "moving", status: StatusIncomplete, installer: "zip", expectedError: ErrInterruptedInstall},
+		{name: "my-plugin", status: StatusInstalled, installer: "fs"},
+		{name: "partial", status: StatusIncomplete, installer: "gzip", expectedError: ErrInterruptedInstall},
+		{name: "stale", status: StatusInstalled, installer: "fs"},
+	}
+
+	require.Len(t, plugins, len(expected))
+
+	for idx, e := range expected {
+		p := plugins[idx]
+
+		assert.Equal(t, e.name, p.Name)
+		assert.Equal(t, "/app/plugins/"+e.name, p.Path)
+		assert.Equal(t, e.status, p.Status, e.name)
+		assert.Equal(t, e.installer, p.Installer, e.name)
+
+		if e.expectedError == nil {
+			assert.NoError(t, p.Problem, e.name)
+		} else {
+			assert.True(t, errors.Is(p.Problem, e.expectedError), "%s: %v", e.name, p.Problem)
+		}
+	}
+
+	installed := plugins[5]
+
+	assert.Equal(t, "1.2.0", installed.Version)
+	assert.Equal(t, "/src", installed.Source)
+	assert.False(t, installed.InstalledAt.Before(start))
+
+	assert.Equal(t, "/src/gone.zip", plugins[2].Source)
+
+	var lockErr *LockError
+
+	require.True(t, errors.As(plugins[3].Problem, &lockErr))
+	assert.Equal(t, 42, lockErr.PID)
+	assert.Equal(t, "other", lockErr.Host)
+}
+
+func TestInstaller_List_NoDestination(t *testing.T) {
+	t.Parallel()
+
+	plugins, err := NewFsInstaller(afero.NewMemMapFs()).List(context.Background(), "/app/plugins")
+
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+}
+
+func TestInstaller_List_InvalidRecord(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "/app/plugins/.my-plugin.install.yaml", []byte("installer: [\n"), 0o644))
+
+	plugins, err := NewFsInstaller(fs).List(context.Background(), "/app/plugins")
+
+	assert.Nil(t, plugins)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/app/plugins/.my-plugin.install.yaml: yaml:")
+}
+
+func TestInstaller_List_FailedInstallRemovesStaleRecord(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "/app/plugins/.my-plugin.install.yaml", []byte("version: 1.0.0\ninstaller: fs\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/src/.plugin.registry.yaml", []byte("name: my-plugin\nversion: 2.0.0\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/src/my-plugin/my-plugin", []byte("v2"), 0o644))
+
+	_, err := NewFsInstaller(fs).Install(context.Background(), "/app/plugins", "/src")
+	require.Error(t, err)
+
+	plugins, err := NewFsInstaller(fs).List(context.Background(), "/app/plugins")
+
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+}
+
+func TestNewInstaller_Kind(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	assert.Equal(t, "fs", NewFsInstaller(fs).kind)
+	assert.Equal(t, "zip", NewZipInstaller(fs).kind)
+	assert.Equal(t, "gzip", NewGzipInstaller(fs).kind)
+}
@@ -0,0 +1,331 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInstallLocked indicates that another process is installing the same plugin to the same destination.
+var ErrInstallLocked = errors.New("install locked")
+
+const (
+	// lockRetryInterval is the interval between two attempts to acquire a lock.
+	lockRetryInterval = 50 * time.Millisecond
+	// takeoverTimeout is the age after which a takeover file is abandoned by a process that died while taking over a
+	// stale lock. Taking over a lock only reads and removes the lock file.
+	takeoverTimeout = 10 * time.Second
+
+	takeoverSuffix = ".takeover"
+)
+
+// WithLockTimeout sets how long an installer waits for another process to finish installing the same plugin to the
+// same destination. By default, it does not wait and fails with ErrInstallLocked, a negative timeout waits until the
+// context is done.
+func WithLockTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.lockTimeout = d
+	}
+}
+
+// LockError indicates that a plugin is locked by another process.
+type LockError struct {
+	Path string
+	PID  int
+	Host string
+}
+
+// Error satisfies the error interface.
+func (e *LockError) Error() string {
+	return fmt.Sprintf("%s: %s by process %d on %q", e.Path, ErrInstallLocked, e.PID, e.Host)
+}
+
+// Is satisfies errors.Is.
+func (e *LockError) Is(target error) bool {
+	return target == ErrInstallLocked //nolint: errorlint,goerr113
+}
+
+// fder is a file that has a file descriptor, such as *os.File.
+type fder interface {
+	Fd() uintptr
+}
+
+// lockInfo is the content of a lock file.
+type lockInfo struct {
+	PID  int    `yaml:"pid"`
+	Host string `yaml:"host"`
+}
+
+// installLock is an advisory lock on dest/<name>. On OS file systems, the lock file is locked with flock(2) and is
+// released by the kernel if the process dies. Otherwise, the lock file is created exclusively and removed when the
+// lock is released, a lock file left by a dead process on the same host is stale and is taken over, see takeOver.
+type installLock struct {
+	fs   afero.Fs
+	path string
+	file afero.File
+}
+
+// lockPath returns the path of the lock file of the plugin, next to the plugin folder.
+func lockPath(dest, name string) string {
+	return filepath.Join(dest, fmt.Sprintf(".%s.lock", name))
+}
+
+// lock acquires the install lock of the plugin, waiting for the lock timeout if it is held by another process.
+func (c *config) lock(ctx context.Context, dest, name string) (*installLock, error) {
+	if err := c.destFs.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+
+	path := lockPath(dest, name)
+	deadline := time.Now().Add(c.lockTimeout)
+
+	for {
+		l, err := tryLock(c.destFs, path)
+		if err == nil || !errors.Is(err, ErrInstallLocked) {
+			return l, err
+		}
+
+		if c.lockTimeout == 0 || (c.lockTimeout > 0 && time.Now().After(deadline)) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func tryLock(fs afero.Fs, path string) (*installLock, error) {
+	if _, ok := fs.(*afero.OsFs); ok && flockSupported {
+		return tryFlock(fs, path)
+	}
+
+	for {
+		f, err := fs.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			defer f.Close() //nolint: errcheck
+
+			l := &installLock{fs: fs, path: path}
+
+			if err := writeLockInfo(f); err != nil {
+				_ = l.release() //nolint: errcheck
+
+				return nil, err
+			}
+
+			return l, nil
+		}
+
+		if !os.IsExist(err) && !errors.Is(err, afero.ErrFileExists) {
+			return nil, err
+		}
+
+		info, err := readLockInfo(fs, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.stale() {
+			return nil, &LockError{Path: path, PID: info.PID, Host: info.Host}
+		}
+
+		if err := takeOver(fs, path, info); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// takeOver removes a stale lock file. Only one process at a time takes over a lock, it holds an exclusive takeover file
+// and removes the lock file only if it is still the stale one, so a process that saw the same stale lock does not
+// remove the lock that another process has taken over in the meantime.
+func takeOver(fs afero.Fs, path string, stale lockInfo) error {
+	guard := path + takeoverSuffix
+
+	f, err := fs.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if !os.IsExist(err) && !errors.Is(err, afero.ErrFileExists) {
+			return err
+		}
+
+		if fi, err := fs.Stat(guard); err == nil && time.Since(fi.ModTime()) > takeoverTimeout {
+			_ = fs.Remove(guard) //nolint: errcheck
+		}
+
+		info, _ := readLockInfo(fs, guard) //nolint: errcheck
+
+		return &LockError{Path: guard, PID: info.PID, Host: info.Host}
+	}
+
+	defer fs.Remove(guard) //nolint: errcheck
+
+	err = writeLockInfo(f)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	info, err := readLockInfo(fs, path)
+	if err != nil || info != stale {
+		return err
+	}
+
+	if err := fs.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func tryFlock(fs afero.Fs, path string) (*installLock, error) {
+	f, err := fs.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, ok := f.(fder)
+	if !ok {
+		_ = f.Close() //nolint: errcheck
+
+		return nil, fmt.Errorf("%s: %w", path, os.ErrInvalid)
+	}
+
+	if err := flock(fd); err != nil {
+		_ = f.Close() //nolint: errcheck
+
+		if !errors.Is(err, ErrInstallLocked) {
+			return nil, err
+		}
+
+		info, _ := readLockInfo(fs, path) //nolint: errcheck
+
+		return nil, &LockError{Path: path, PID: info.PID, Host: info.Host}
+	}
+
+	l := &installLock{fs: fs, path: path, file: f}
+
+	if err := f.Truncate(0); err != nil {
+		_ = l.release() //nolint: errcheck
+
+		return nil, err
+	}
+
+	if err := writeLockInfo(f); err != nil {
+		_ = l.release() //nolint: errcheck
+
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// release releases the lock. The flock(2) lock files are emptied and kept because removing them would let another
+// process lock a file that is already unlinked.
+func (l *installLock) release() error {
+	if l.file == nil {
+		return l.fs.Remove(l.path)
+	}
+
+	defer l.file.Close() //nolint: errcheck
+
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+
+	return funlock(l.file.(fder))
+}
+
+// lockHeld checks whether the lock file is held by a running process, without waiting for it. On OS file systems, the
+// lock file is locked with flock(2) and unlocked at once, so the PID that a dead process left in it is ignored.
+func lockHeld(fs afero.Fs, path string) (lockInfo, bool, error) {
+	info, err := readLockInfo(fs, path)
+	if err != nil || info.PID == 0 {
+		return info, false, err
+	}
+
+	if _, ok := fs.(*afero.OsFs); !ok || !flockSupported {
+		return info, !info.stale(), nil
+	}
+
+	f, err := fs.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return info, false, nil
+		}
+
+		return info, false, err
+	}
+
+	defer f.Close() //nolint: errcheck
+
+	fd, ok := f.(fder)
+	if !ok {
+		return info, false, fmt.Errorf("%s: %w", path, os.ErrInvalid)
+	}
+
+	if err := flock(fd); err != nil {
+		if errors.Is(err, ErrInstallLocked) {
+			return info, true, nil
+		}
+
+		return info, false, err
+	}
+
+	return info, false, funlock(fd)
+}
+
+// stale checks whether the process that holds the lock is dead. Processes on other hosts are assumed to be alive.
+func (i lockInfo) stale() bool {
+	host, err := os.Hostname()
+	if err != nil || host != i.Host {
+		return false
+	}
+
+	return !processAlive(i.PID)
+}
+
+func writeLockInfo(f afero.File) error {
+	host, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(lockInfo{PID: os.Getpid(), Host: host})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteAt(b, 0)
+
+	return err
+}
+
+// readLockInfo reads the lock file. A lock file that is being written or is corrupted has no PID and is not stale.
+func readLockInfo(fs afero.Fs, path string) (lockInfo, error) {
+	var info lockInfo
+
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return info, nil
+		}
+
+		return info, err
+	}
+
+	_ = yaml.Unmarshal(b, &info) //nolint: errcheck
+
+	return info, nil
+}
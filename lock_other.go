@@ -0,0 +1,37 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package fs
+
+import (
+	"errors"
+	"os"
+)
+
+const flockSupported = false
+
+var errFlockNotSupported = errors.New("flock is not supported")
+
+func flock(fder) error {
+	return errFlockNotSupported
+}
+
+func funlock(fder) error {
+	return errFlockNotSupported
+}
+
+// processAlive checks whether the process exists, which os.FindProcess does on windows but not on the other systems.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return true
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	_ = p.Release() //nolint: errcheck
+
+	return true
+}
@@ -0,0 +1,369 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/afero/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/aferomock"
+)
+
+// expectInstallLock expects the installer to lock and release dest/<name>.
+func expectInstallLock(fs *aferomock.Fs, dest, name string) {
+	path := lockPath(dest, name)
+
+	fs.On("MkdirAll", dest, os.FileMode(0o755)).
+		Return(nil)
+
+	fs.On("OpenFile", path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.FileMode(0o644)).
+		Return(mem.NewFileHandle(mem.CreateFile(path)), nil)
+
+	fs.On("Remove", path).
+		Return(nil)
+}
+
+func writeLockFile(t *testing.T, fs afero.Fs, path string, pid int, host string) {
+	t.Helper()
+
+	content := fmt.Sprintf("pid: %d\nhost: %s\n", pid, host)
+
+	require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+}
+
+func TestConfig_Lock(t *testing.T) {
+	t.Parallel()
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		scenario      string
+		pid           int
+		host          string
+		expectedError string
+	}{
+		{
+			scenario: "not locked",
+		},
+		{
+			scenario:      "locked by a live process",
+			pid:           os.Getpid(),
+			host:          host,
+			expectedError: fmt.Sprintf("/app/plugins/.my-plugin.lock: install locked by process %d on %q", os.Getpid(), host),
+		},
+		{
+			scenario:      "locked by another host",
+			pid:           1,
+			host:          "another-host",
+			expectedError: `/app/plugins/.my-plugin.lock: install locked by process 1 on "another-host"`,
+		},
+		{
+			scenario: "stale",
+			pid:      1 << 30,
+			host:     host,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			if tc.pid != 0 {
+				writeLockFile(t, fs, "/app/plugins/.my-plugin.lock", tc.pid, tc.host)
+			}
+
+			cfg := newConfig(fs)
+			l, err := cfg.lock(context.Background(), "/app/plugins", "my-plugin")
+
+			if tc.expectedError != "" {
+				assert.Nil(t, l)
+				require.EqualError(t, err, tc.expectedError)
+				assert.ErrorIs(t, err, ErrInstallLocked)
+
+				var lockErr *LockError
+
+				require.True(t, errors.As(err, &lockErr))
+				assert.Equal(t, tc.pid, lockErr.PID)
+				assert.Equal(t, tc.host, lockErr.Host)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			info, err := readLockInfo(fs, "/app/plugins/.my-plugin.lock")
+			require.NoError(t, err)
+			assert.Equal(t, lockInfo{PID: os.Getpid(), Host: host}, info)
+
+			require.NoError(t, l.release())
+
+			_, err = fs.Stat("/app/plugins/.my-plugin.lock")
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestConfig_Lock_TakeOver(t *testing.T) {
+	t.Parallel()
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	const (
+		path  = "/app/plugins/.my-plugin.lock"
+		guard = "/app/plugins/.my-plugin.lock.takeover"
+	)
+
+	testCases := []struct {
+		scenario      string
+		guardAge      time.Duration
+		timeout       time.Duration
+		expectedError string
+	}{
+		{
+			scenario:      "another process is taking over",
+			expectedError: fmt.Sprintf("%s: install locked by process %d on %q", guard, os.Getpid(), host),
+		},
+		{
+			scenario: "abandoned takeover",
+			guardAge: 2 * takeoverTimeout,
+			timeout:  time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			writeLockFile(t, fs, path, 1<<30, host)
+			writeLockFile(t, fs, guard, os.Getpid(), host)
+
+			modTime := time.Now().Add(-tc.guardAge)
+
+			require.NoError(t, fs.Chtimes(guard, modTime, modTime))
+
+			cfg := newConfig(fs, WithLockTimeout(tc.timeout))
+			l, err := cfg.lock(context.Background(), "/app/plugins", "my-plugin")
+
+			if tc.expectedError != "" {
+				assert.Nil(t, l)
+				require.EqualError(t, err, tc.expectedError)
+
+				// The stale lock is left to the process that is taking it over.
+				info, err := readLockInfo(fs, path)
+				require.NoError(t, err)
+				assert.Equal(t, 1<<30, info.PID)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, l.release())
+
+			_, err = fs.Stat(guard)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestTakeOver_LockTakenInTheMeantime(t *testing.T) {
+	t.Parallel()
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+
+	// Another process has replaced the stale lock after it was read.
+	writeLockFile(t, fs, "/app/plugins/.my-plugin.lock", os.Getpid(), host)
+
+	err = takeOver(fs, "/app/plugins/.my-plugin.lock", lockInfo{PID: 1 << 30, Host: host})
+	require.NoError(t, err)
+
+	info, err := readLockInfo(fs, "/app/plugins/.my-plugin.lock")
+	require.NoError(t, err)
+	assert.Equal(t, lockInfo{PID: os.Getpid(), Host: host}, info)
+
+	_, err = fs.Stat("/app/plugins/.my-plugin.lock.takeover")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestConfig_Lock_TakeOverConcurrently(t *testing.T) {
+	t.Parallel()
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+
+	writeLockFile(t, fs, "/app/plugins/.my-plugin.lock", 1<<30, host)
+
+	var (
+		wg       sync.WaitGroup
+		acquired int32
+	)
+
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			cfg := newConfig(fs)
+
+			// The locks are not released, so only one of them can be acquired.
+			if _, err := cfg.lock(context.Background(), "/app/plugins", "my-plugin"); err == nil {
+				atomic.AddInt32(&acquired, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), acquired)
+}
+
+func TestConfig_Lock_Wait(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	cfg := newConfig(fs, WithLockTimeout(time.Second))
+
+	l, err := cfg.lock(context.Background(), "/app/plugins", "my-plugin")
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(3 * lockRetryInterval)
+
+		_ = l.release() //nolint: errcheck
+	}()
+
+	l, err = cfg.lock(context.Background(), "/app/plugins", "my-plugin")
+	require.NoError(t, err)
+	require.NoError(t, l.release())
+}
+
+func TestConfig_Lock_Timeout(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	cfg := newConfig(fs, WithLockTimeout(2*lockRetryInterval))
+
+	_, err := cfg.lock(context.Background(), "/app/plugins", "my-plugin")
+	require.NoError(t, err)
+
+	_, err = cfg.lock(context.Background(), "/app/plugins", "my-plugin")
+	assert.ErrorIs(t, err, ErrInstallLocked)
+}
+
+func TestConfig_Lock_ContextDone(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	cfg := newConfig(fs, WithLockTimeout(-1))
+
+	_, err := cfg.lock(context.Background(), "/app/plugins", "my-plugin")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*lockRetryInterval)
+	defer cancel()
+
+	_, err = cfg.lock(ctx, "/app/plugins", "my-plugin")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConfig_Lock_OsFs(t *testing.T) {
+	t.Parallel()
+
+	if !flockSupported {
+		t.Skip("flock is not supported")
+	}
+
+	dest := filepath.Join(t.TempDir(), "plugins")
+	cfg := newConfig(afero.NewOsFs())
+
+	l, err := cfg.lock(context.Background(), dest, "my-plugin")
+	require.NoError(t, err)
+
+	_, err = cfg.lock(context.Background(), dest, "my-plugin")
+	assert.ErrorIs(t, err, ErrInstallLocked)
+	assert.Contains(t, err.Error(), fmt.Sprintf("by process %d", os.Getpid()))
+
+	require.NoError(t, l.release())
+
+	l, err = cfg.lock(context.Background(), dest, "my-plugin")
+	require.NoError(t, err)
+	require.NoError(t, l.release())
+}
+
+func TestLockHeld_OsFs(t *testing.T) {
+	t.Parallel()
+
+	if !flockSupported {
+		t.Skip("flock is not supported")
+	}
+
+	fs := afero.NewOsFs()
+	dest := filepath.Join(t.TempDir(), "plugins")
+	path := lockPath(dest, "my-plugin")
+
+	cfg := newConfig(fs)
+
+	l, err := cfg.lock(context.Background(), dest, "my-plugin")
+	require.NoError(t, err)
+
+	info, held, err := lockHeld(fs, path)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, os.Getpid(), info.PID)
+
+	require.NoError(t, l.release())
+
+	// A process that died while holding the lock leaves its PID in the lock file.
+	writeLockFile(t, fs, path, os.Getpid(), "localhost")
+
+	_, held, err = lockHeld(fs, path)
+	require.NoError(t, err)
+	assert.False(t, held)
+}
+
+func TestFsInstaller_Install_Locked(t *testing.T) {
+	t.Parallel()
+
+	fs := newMultiPluginFs(t, map[string]string{
+		"/src/.plugin.registry.yaml": "name: my-plugin",
+		"/src/my-plugin/my-plugin":   "#!/bin/bash\n",
+	})
+
+	writeLockFile(t, fs, "/app/plugins/.my-plugin.lock", 1, "another-host")
+
+	p, err := NewFsInstaller(fs).Install(context.Background(), "/app/plugins", "/src")
+
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInstallLocked)
+	assert.Contains(t, err.Error(), "could not lock plugin: ")
+
+	var installErr *InstallError
+
+	require.True(t, errors.As(err, &installErr))
+	assert.Equal(t, PhaseLock, installErr.Phase)
+
+	_, err = fs.Stat("/app/plugins/my-plugin")
+	assert.True(t, os.IsNotExist(err))
+}
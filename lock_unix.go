@@ -0,0 +1,35 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package fs
+
+import (
+	"errors"
+	"syscall"
+)
+
+const flockSupported = true
+
+func flock(f fder) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrInstallLocked
+	}
+
+	return err
+}
+
+func funlock(f fder) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// processAlive checks whether the process exists by sending the signal 0 to it.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return true
+	}
+
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
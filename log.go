@@ -0,0 +1,56 @@
+package fs
+
+import (
+	"context"
+
+	"github.com/bool64/ctxd"
+)
+
+type loggerCtxKey struct{}
+
+// SpanFunc starts a span with the name, for example to trace the installations with OpenTelemetry. It returns the
+// context of the span and a function that ends the span with the error of the operation, if any.
+type SpanFunc func(ctx context.Context, name string) (context.Context, func(err error))
+
+// ContextWithLogger returns a context that carries the logger for the installers that have no WithLogger option.
+func ContextWithLogger(ctx context.Context, l ctxd.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, l)
+}
+
+// WithLogger sets the logger of the installation events. Without it, the logger of the context is used, see
+// ContextWithLogger.
+func WithLogger(l ctxd.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// WithSpan sets the function that starts the spans of the installations, which are "install", "detect", "extract" and
+// "verify".
+func WithSpan(f SpanFunc) Option {
+	return func(c *config) {
+		c.span = f
+	}
+}
+
+// log returns the logger of the installer, or of the context, or a no-op logger.
+func (c *config) log(ctx context.Context) ctxd.Logger { //nolint: ireturn
+	if c.logger != nil {
+		return c.logger
+	}
+
+	if l, ok := ctx.Value(loggerCtxKey{}).(ctxd.Logger); ok {
+		return l
+	}
+
+	return ctxd.NoOpLogger{}
+}
+
+// startSpan starts a span if the installer has a span function.
+func (c *config) startSpan(ctx context.Context, name string) (context.Context, func(err error)) {
+	if c.span == nil {
+		return ctx, func(error) {}
+	}
+
+	return c.span(ctx, name)
+}
@@ -0,0 +1,167 @@
+package fs
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/bool64/ctxd"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loggedMessages(l *ctxd.LoggerMock) []string {
+	l.Lock()
+	defer l.Unlock()
+
+	result := make([]string, 0, len(l.LoggedEntries))
+
+	for _, e := range l.LoggedEntries {
+		result = append(result, e.Level+": "+e.Message)
+	}
+
+	return result
+}
+
+func newLoggingFs(t *testing.T, mode int64) afero.Fs {
+	t.Helper()
+
+	fs := newMultiPluginFs(t, map[string]string{"/src/.plugin.registry.yaml": "name: my-plugin"})
+	content := newTarGz(t,
+		archiveEntry{name: "my-plugin/my-plugin", content: "#!/bin/bash\n", mode: mode},
+		archiveEntry{name: "my-plugin/README.md", content: "my-plugin", mode: 0o644},
+	)
+
+	require.NoError(t, afero.WriteFile(fs, "/src/my-plugin.tar.gz", content, 0o644))
+
+	return fs
+}
+
+func TestArchiveInstaller_Install_Logger(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		mode     int64
+		option   bool
+		expected []string
+	}{
+		{
+			scenario: "option",
+			mode:     0o755,
+			option:   true,
+			expected: []string{
+				"debug: plugin detected",
+				"info: installing plugin",
+				"debug: extracted file",
+				"debug: extracted file",
+				"info: plugin installed",
+				"debug: plugin validated",
+			},
+		},
+		{
+			scenario: "context with rollback",
+			mode:     0o644,
+			expected: []string{
+				"debug: plugin detected",
+				"info: installing plugin",
+				"debug: extracted file",
+				"debug: extracted file",
+				"info: plugin installed",
+				"warn: rolling back plugin",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			l := &ctxd.LoggerMock{}
+			ctx := context.Background()
+
+			var opts []Option
+
+			if tc.option {
+				opts = append(opts, WithLogger(l))
+			} else {
+				ctx = ContextWithLogger(ctx, l)
+			}
+
+			_, _ = NewGzipInstaller(newLoggingFs(t, tc.mode), opts...).Install(ctx, "/app/plugins", "/src/my-plugin.tar.gz") //nolint: errcheck
+
+			assert.Equal(t, tc.expected, loggedMessages(l))
+		})
+	}
+}
+
+func TestFsInstaller_Install_Logger(t *testing.T) {
+	t.Parallel()
+
+	fs := newMultiPluginFs(t, map[string]string{
+		"/src/.plugin.registry.yaml": "name: my-plugin",
+		"/src/my-plugin/my-plugin":   "#!/bin/bash\n",
+	})
+
+	l := &ctxd.LoggerMock{}
+
+	_, err := NewFsInstaller(fs, WithLogger(l)).Install(context.Background(), "/app/plugins", "/src")
+	require.NoError(t, err)
+
+	expected := []string{
+		"debug: plugin detected",
+		"info: installing plugin",
+		"debug: copying file",
+		"info: plugin installed",
+		"debug: plugin validated",
+	}
+
+	assert.Equal(t, expected, loggedMessages(l))
+}
+
+func TestInstaller_Install_Span(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		spans []string
+	)
+
+	span := func(ctx context.Context, name string) (context.Context, func(err error)) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		spans = append(spans, "start "+name)
+
+		return ctx, func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			result := "ok"
+			if err != nil {
+				result = "error"
+			}
+
+			spans = append(spans, "end "+name+" "+result)
+		}
+	}
+
+	_, err := NewGzipInstaller(newLoggingFs(t, 0o644), WithSpan(span)).
+		Install(context.Background(), "/app/plugins", "/src/my-plugin.tar.gz")
+	require.Error(t, err)
+
+	expected := []string{
+		"start install",
+		"start detect",
+		"end detect ok",
+		"start extract",
+		"end extract ok",
+		"start verify",
+		"end verify error",
+		"end install error",
+	}
+
+	assert.Equal(t, expected, spans)
+}
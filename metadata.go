@@ -0,0 +1,44 @@
+package fs
+
+import (
+	"path/filepath"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// metadata is the plugin metadata that is specific to the file system installers. It is read from the same
+// .plugin.registry.yaml as plugin.Plugin, for example:
+//
+//	name: my-plugin
+//	files:
+//	  exclude:
+//	    - "**/*_test.go"
+//	    - README.md
+//	dependencies:
+//	  my-other-plugin: ^1.0
+//	hooks:
+//	  post-install:
+//	    - command: ./my-plugin
+//	      args: [init]
+type metadata struct {
+	Files        fileFilter        `yaml:"files"`
+	Dependencies map[string]string `yaml:"dependencies"`
+	Hooks        Hooks             `yaml:"hooks"`
+}
+
+func loadMetadata(fs afero.Fs, path string) (*metadata, error) {
+	data, err := afero.ReadFile(fs, filepath.Join(path, plugin.MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var m metadata
+
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
@@ -0,0 +1,78 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMetadata(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		content       string
+		expected      *metadata
+		expectedError string
+	}{
+		{
+			scenario:      "no metadata",
+			expectedError: "open /tmp/.plugin.registry.yaml: file does not exist",
+		},
+		{
+			scenario:      "invalid metadata",
+			content:       "files: 42",
+			expectedError: "yaml: unmarshal errors:\n  line 1: cannot unmarshal !!int `42` into fs.fileFilter",
+		},
+		{
+			scenario: "success",
+			content:  "name: my-plugin\nfiles:\n  include: [my-plugin]\n  exclude: [README.md]\n",
+			expected: &metadata{
+				Files: fileFilter{
+					Include: []string{"my-plugin"},
+					Exclude: []string{"README.md"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			if tc.content != "" {
+				require.NoError(t, afero.WriteFile(fs, "/tmp/.plugin.registry.yaml", []byte(tc.content), 0o644))
+			}
+
+			actual, err := loadMetadata(fs, "/tmp")
+
+			assert.Equal(t, tc.expected, actual)
+
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestFsInstaller_Install_InvalidFilter(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "/tmp/.plugin.registry.yaml", []byte("name: my-plugin\nfiles:\n  exclude: ['[a-']\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/tmp/my-plugin/my-plugin", []byte("#!/bin/bash\n"), 0o755))
+
+	result, err := NewFsInstaller(fs).Install(context.Background(), "/app/plugins", "/tmp")
+
+	assert.Nil(t, result)
+	require.EqualError(t, err, "invalid file filter: [a-: syntax error in pattern")
+}
@@ -0,0 +1,172 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/nhatthm/plugin-registry/installer"
+	"github.com/nhatthm/plugin-registry/plugin"
+)
+
+// Observer observes the installations, for example to export metrics. The installers call it concurrently when they
+// run concurrently.
+type Observer interface {
+	// ObserveInstall is called at the end of every installation with the name of the plugin, if it is known, the
+	// duration and the error, if any. Use ErrorKind to classify the errors.
+	ObserveInstall(ctx context.Context, plugin string, d time.Duration, err error)
+	// ObservePhase is called at the end of the detect, extract and verify phases with their duration and error.
+	ObservePhase(ctx context.Context, phase Phase, d time.Duration, err error)
+	// ObserveEntry is called for every installed file with the number of bytes read from the source file for it, which
+	// are compressed for the archives and zero for the files that are cloned or linked, and the size of the file.
+	ObserveEntry(ctx context.Context, entry string, read, written int64)
+}
+
+// NopObserver is an observer that does nothing. It is the default observer.
+type NopObserver struct{}
+
+var _ Observer = NopObserver{}
+
+// ObserveInstall does nothing.
+func (NopObserver) ObserveInstall(context.Context, string, time.Duration, error) {}
+
+// ObservePhase does nothing.
+func (NopObserver) ObservePhase(context.Context, Phase, time.Duration, error) {}
+
+// ObserveEntry does nothing.
+func (NopObserver) ObserveEntry(context.Context, string, int64, int64) {}
+
+// WithObserver sets the observer of the installations.
+func WithObserver(o Observer) Option {
+	return func(c *config) {
+		c.observer = o
+	}
+}
+
+// ErrorKind classifies an installation error for metrics: "locked", "canceled", "no_installer", "unsafe_entry",
+// "invalid_plugin", "not_found", "permission", "no_space", or the phase of the InstallError, or "unknown". It returns
+// an empty string if there is no error.
+func ErrorKind(err error) string {
+	kinds := []struct {
+		kind string
+		errs []error
+	}{
+		{kind: "locked", errs: []error{ErrInstallLocked}},
+		{kind: "canceled", errs: []error{context.Canceled, context.DeadlineExceeded}},
+		{kind: "no_installer", errs: []error{installer.ErrNoInstaller}},
+		{kind: "unsafe_entry", errs: []error{ErrIllegalFilePath, ErrIllegalFileMode, ErrDuplicateEntry}},
+		{kind: "invalid_plugin", errs: []error{
+			ErrEntrypointMissing, ErrEntrypointNotRegular, ErrEntrypointNotExecutable, ErrPlatformMismatch, ErrUnknownBinaryFormat,
+		}},
+		{kind: "not_found", errs: []error{ErrPluginNotInstalled, os.ErrNotExist}},
+		{kind: "permission", errs: []error{os.ErrPermission}},
+		{kind: "no_space", errs: []error{syscall.ENOSPC}},
+	}
+
+	if err == nil {
+		return ""
+	}
+
+	for _, k := range kinds {
+		for _, e := range k.errs {
+			if errors.Is(err, e) {
+				return k.kind
+			}
+		}
+	}
+
+	var ie *InstallError
+
+	if errors.As(err, &ie) {
+		return string(ie.Phase)
+	}
+
+	return "unknown"
+}
+
+// startPhase starts the span of the phase, and observes its duration when it ends.
+func (c *config) startPhase(ctx context.Context, phase Phase) (context.Context, func(err error)) {
+	ctx, endSpan := c.startSpan(ctx, string(phase))
+	start := time.Now()
+
+	return ctx, func(err error) {
+		c.observer.ObservePhase(ctx, phase, time.Since(start), err)
+		endSpan(err)
+	}
+}
+
+// observeInstall runs an installation in the "install" span and reports it to the observer. The plugin is detected in
+// the detect phase, and then installed.
+func (c *config) observeInstall(
+	ctx context.Context,
+	detect func(ctx context.Context) (*plugin.Plugin, error),
+	install func(ctx context.Context, p *plugin.Plugin) error,
+) (_ *plugin.Plugin, err error) {
+	var name string
+
+	ctx, end := c.startSpan(ctx, "install")
+	start := time.Now()
+
+	defer func() {
+		c.observer.ObserveInstall(ctx, name, time.Since(start), err)
+		end(err)
+	}()
+
+	detectCtx, endDetect := c.startPhase(ctx, PhaseDetect)
+
+	p, err := detect(detectCtx)
+
+	endDetect(err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	name = p.Name
+
+	if err := install(ctx, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// observeDetectError reports a failure to detect several plugins as a failed installation.
+func (c *config) observeDetectError(ctx context.Context, err error) error {
+	_, err = c.observeInstall(ctx, func(context.Context) (*plugin.Plugin, error) {
+		return nil, err
+	}, nil)
+
+	return err
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	io.Reader
+
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+
+	return n, err
+}
+
+// countingReaderAt counts the bytes read from a reader at.
+type countingReaderAt struct {
+	io.ReaderAt
+
+	n int64
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.ReaderAt.ReadAt(p, off)
+	r.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,271 @@
+package fs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nhatthm/plugin-registry/installer"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKind(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		err      error
+		expected string
+	}{
+		{scenario: "nil"},
+		{scenario: "locked", err: &LockError{Path: "/app/plugins/.my-plugin.lock"}, expected: "locked"},
+		{scenario: "canceled", err: context.Canceled, expected: "canceled"},
+		{scenario: "no installer", err: &DetectionError{}, expected: "no_installer"},
+		{scenario: "illegal path", err: entryError("../evil", ErrIllegalFilePath), expected: "unsafe_entry"},
+		{scenario: "illegal mode", err: &ModeError{}, expected: "unsafe_entry"},
+		{scenario: "platform", err: ErrPlatformMismatch, expected: "invalid_plugin"},
+		{scenario: "missing entrypoint", err: fmt.Errorf("/app/plugins/my-plugin/my-plugin: %w", ErrEntrypointMissing), expected: "invalid_plugin"},
+		{scenario: "not found", err: fmt.Errorf("open: %w", os.ErrNotExist), expected: "not_found"},
+		{scenario: "permission", err: os.ErrPermission, expected: "permission"},
+		{scenario: "no space", err: &os.PathError{Op: "write", Path: "/tmp", Err: syscall.ENOSPC}, expected: "no_space"},
+		{
+			scenario: "phase",
+			err:      &InstallError{Phase: PhaseParse, Err: errors.New("yaml error")},
+			expected: "parse",
+		},
+		{scenario: "unknown", err: errors.New("error"), expected: "unknown"},
+		{scenario: "no installer sentinel", err: installer.ErrNoInstaller, expected: "no_installer"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, ErrorKind(tc.err))
+		})
+	}
+}
+
+type observedInstall struct {
+	plugin string
+	kind   string
+}
+
+type observerStub struct {
+	NopObserver
+
+	mu       sync.Mutex
+	installs []observedInstall
+	phases   []Phase
+	entries  []observedEntry
+}
+
+type observedEntry struct {
+	entry         string
+	read, written int64
+}
+
+func (o *observerStub) ObserveInstall(_ context.Context, plugin string, _ time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.installs = append(o.installs, observedInstall{plugin: plugin, kind: ErrorKind(err)})
+}
+
+func (o *observerStub) ObservePhase(_ context.Context, phase Phase, _ time.Duration, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.phases = append(o.phases, phase)
+}
+
+func (o *observerStub) ObserveEntry(_ context.Context, entry string, read, written int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.entries = append(o.entries, observedEntry{entry: entry, read: read, written: written})
+}
+
+func TestArchiveInstaller_ObserveEntry(t *testing.T) {
+	t.Parallel()
+
+	files := []archiveEntry{
+		{name: "my-plugin/my-plugin", content: "#!/bin/bash\n"},
+		{name: "my-plugin/README.md", content: strings.Repeat("my-plugin\n", 100)},
+	}
+
+	testCases := []struct {
+		scenario        string
+		path            string
+		archive         func(t *testing.T) []byte
+		expectedEntries []string
+		expectedSizes   []int64
+	}{
+		{
+			scenario: "zip",
+			path:     "/tmp/my-plugin.zip",
+			archive: func(t *testing.T) []byte {
+				t.Helper()
+
+				return newZip(t, files...)
+			},
+			expectedEntries: []string{"my-plugin/my-plugin", "my-plugin/README.md"},
+			expectedSizes:   []int64{12, 1000},
+		},
+		{
+			scenario: "tar.gz",
+			path:     "/tmp/my-plugin.tar.gz",
+			archive: func(t *testing.T) []byte {
+				t.Helper()
+
+				return newTarGz(t, files...)
+			},
+			expectedEntries: []string{"my-plugin/my-plugin", "my-plugin/README.md"},
+			expectedSizes:   []int64{12, 1000},
+		},
+		{
+			scenario: "gz",
+			path:     "/tmp/my-plugin.gz",
+			archive: func(t *testing.T) []byte {
+				t.Helper()
+
+				buf := new(bytes.Buffer)
+				w := gzip.NewWriter(buf)
+
+				_, err := w.Write([]byte("#!/bin/bash\n"))
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+
+				return buf.Bytes()
+			},
+			expectedEntries: []string{"my-plugin"},
+			expectedSizes:   []int64{12},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+			archive := tc.archive(t)
+
+			require.NoError(t, afero.WriteFile(fs, "/tmp/.plugin.registry.yaml", []byte("name: my-plugin"), 0o644))
+			require.NoError(t, afero.WriteFile(fs, tc.path, archive, 0o755))
+
+			o := &observerStub{}
+			i, err := newArchiveInstallerFor(fs, tc.path, WithObserver(o))
+			require.NoError(t, err)
+
+			_, err = i.Install(context.Background(), "/app/plugins", tc.path)
+			require.NoError(t, err)
+
+			require.Len(t, o.entries, len(tc.expectedEntries))
+
+			var read int64
+
+			for idx, e := range o.entries {
+				assert.Equal(t, tc.expectedEntries[idx], e.entry)
+				assert.Equal(t, tc.expectedSizes[idx], e.written, e.entry)
+				assert.GreaterOrEqual(t, e.read, int64(0), e.entry)
+
+				read += e.read
+			}
+
+			// The gzip reader reads ahead, so only the total of the bytes read is known.
+			assert.Positive(t, read)
+			assert.LessOrEqual(t, read, int64(len(archive)))
+		})
+	}
+}
+
+func TestInstaller_Observer(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"/src/.plugin.registry.yaml":    "plugins:\n  - name: app\n    dependencies:\n      lib: \"*\"\n  - name: lib\n",
+		"/src/app/app":                  "#!/bin/bash\n",
+		"/src/lib/lib":                  "#!/bin/bash\n",
+		"/single/.plugin.registry.yaml": "name: single\n",
+		"/single/single/single":         "#!/bin/bash\n",
+	}
+
+	testCases := []struct {
+		scenario         string
+		install          func(ctx context.Context, i *Installer) error
+		expectedInstalls []observedInstall
+	}{
+		{
+			scenario: "install",
+			install: func(ctx context.Context, i *Installer) error {
+				_, err := i.Install(ctx, "/app/plugins", "/single")
+
+				return err
+			},
+			expectedInstalls: []observedInstall{{plugin: "single"}},
+		},
+		{
+			scenario: "install all",
+			install: func(ctx context.Context, i *Installer) error {
+				_, err := i.InstallAll(ctx, "/app/plugins", "/src")
+
+				return err
+			},
+			expectedInstalls: []observedInstall{{plugin: "app"}, {plugin: "lib"}},
+		},
+		{
+			scenario: "install all unknown plugin",
+			install: func(ctx context.Context, i *Installer) error {
+				_, err := i.InstallAll(ctx, "/app/plugins", "/src", "unknown")
+
+				return err
+			},
+			expectedInstalls: []observedInstall{{kind: "detect"}},
+		},
+		{
+			scenario: "install with dependencies",
+			install: func(ctx context.Context, i *Installer) error {
+				_, err := i.InstallWithDependencies(ctx, "/app/plugins", []string{"/src"}, "app")
+
+				return err
+			},
+			expectedInstalls: []observedInstall{{plugin: "lib"}, {plugin: "app"}},
+		},
+		{
+			scenario: "install with dependencies not found",
+			install: func(ctx context.Context, i *Installer) error {
+				_, err := i.InstallWithDependencies(ctx, "/app/plugins", []string{"/unknown"}, "app")
+
+				return err
+			},
+			expectedInstalls: []observedInstall{{kind: "not_found"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			o := &observerStub{}
+			i := NewFsInstaller(newMultiPluginFs(t, files), WithObserver(o))
+
+			_ = tc.install(context.Background(), i) //nolint: errcheck
+
+			assert.Equal(t, tc.expectedInstalls, o.installs)
+			assert.Contains(t, o.phases, PhaseDetect)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrIllegalFileMode indicates that the file mode is not allowed.
+var ErrIllegalFileMode = errors.New("illegal file mode")
+
+const (
+	// modeBits are the bits that a mode policy controls.
+	modeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	// defaultAllowedMode is the mask of the allowed bits when the mode policy has none.
+	defaultAllowedMode os.FileMode = 0o775
+	// defaultMinDirMode is the minimum mode of the directories of the default mode policy.
+	defaultMinDirMode os.FileMode = 0o755
+	// ownerDirMode is always set on the directories, the installers could not extract the files in them otherwise.
+	ownerDirMode os.FileMode = 0o700
+)
+
+// ModePolicy controls the mode of the files and directories extracted from an archive. On OS file systems, the process
+// umask still applies when the files and directories are created.
+type ModePolicy struct {
+	// Allowed is the mask of the allowed permission and special bits. The other bits are stripped, or rejected if
+	// Reject is set. A zero mask means the default mask, 0o775, use os.ModePerm to allow every permission bit.
+	Allowed os.FileMode
+	// Umask is cleared from the mode of every file and directory.
+	Umask os.FileMode
+	// MinFileMode is the minimum mode of every file, for example 0o644.
+	MinFileMode os.FileMode
+	// MinDirMode is the minimum mode of every directory, for example 0o755. The owner always has the read, write and
+	// execute permissions on the directories, so the installers can extract the files in them.
+	MinDirMode os.FileMode
+	// Reject makes the installers fail with a ModeError instead of stripping the bits that are not allowed.
+	Reject bool
+}
+
+// DefaultModePolicy returns the default mode policy, which strips the setuid, setgid, sticky and world-writable bits,
+// and makes the directories at least 0o755.
+func DefaultModePolicy() ModePolicy {
+	return ModePolicy{
+		Allowed:    defaultAllowedMode,
+		MinDirMode: defaultMinDirMode,
+	}
+}
+
+// WithModePolicy sets the mode policy for the extracted files and directories.
+func WithModePolicy(p ModePolicy) Option {
+	return func(c *config) {
+		c.modePolicy = p
+	}
+}
+
+// ModeError indicates that an extracted file or directory has bits that are not allowed by the mode policy.
+type ModeError struct {
+	Path      string
+	Mode      os.FileMode
+	Forbidden os.FileMode
+}
+
+// Error satisfies the error interface.
+func (e *ModeError) Error() string {
+	return fmt.Sprintf("%s: %s: %s has forbidden bits %s", e.Path, ErrIllegalFileMode, e.Mode, e.Forbidden)
+}
+
+// Is satisfies errors.Is.
+func (e *ModeError) Is(target error) bool {
+	return target == ErrIllegalFileMode //nolint: errorlint,goerr113
+}
+
+// apply returns the mode of the file or directory at the path, according to the policy.
+func (p ModePolicy) apply(path string, mode os.FileMode) (os.FileMode, error) {
+	result := mode & modeBits
+
+	allowed := p.Allowed
+	if allowed == 0 {
+		allowed = defaultAllowedMode
+	}
+
+	if forbidden := result &^ allowed; forbidden != 0 {
+		if p.Reject {
+			return 0, &ModeError{Path: path, Mode: mode, Forbidden: forbidden}
+		}
+
+		result &= allowed
+	}
+
+	result &^= p.Umask
+
+	if mode.IsDir() {
+		return result | p.MinDirMode | ownerDirMode, nil
+	}
+
+	return result | p.MinFileMode, nil
+}
@@ -0,0 +1,150 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModePolicy_Apply(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		policy        ModePolicy
+		mode          os.FileMode
+		expected      os.FileMode
+		expectedError string
+	}{
+		{
+			scenario: "default policy strips setuid",
+			policy:   DefaultModePolicy(),
+			mode:     os.ModeSetuid | 0o755,
+			expected: 0o755,
+		},
+		{
+			scenario: "default policy strips world-writable",
+			policy:   DefaultModePolicy(),
+			mode:     os.ModeDir | os.ModeSticky | 0o777,
+			expected: 0o775,
+		},
+		{
+			scenario: "default policy makes directories traversable",
+			policy:   DefaultModePolicy(),
+			mode:     os.ModeDir | 0o644,
+			expected: 0o755,
+		},
+		{
+			scenario: "directories are writable by the owner",
+			policy:   ModePolicy{Allowed: os.ModePerm, Umask: 0o777},
+			mode:     os.ModeDir | 0o555,
+			expected: 0o700,
+		},
+		{
+			scenario: "zero allowed mask is the default mask",
+			policy:   ModePolicy{Umask: 0o022},
+			mode:     os.ModeSetuid | 0o777,
+			expected: 0o755,
+		},
+		{
+			scenario: "umask",
+			policy:   ModePolicy{Allowed: os.ModePerm, Umask: 0o027},
+			mode:     0o777,
+			expected: 0o750,
+		},
+		{
+			scenario: "min file mode",
+			policy:   ModePolicy{Allowed: os.ModePerm, Umask: 0o077, MinFileMode: 0o644, MinDirMode: 0o755},
+			mode:     0o600,
+			expected: 0o644,
+		},
+		{
+			scenario: "min dir mode",
+			policy:   ModePolicy{Allowed: os.ModePerm, MinFileMode: 0o644, MinDirMode: 0o755},
+			mode:     os.ModeDir | 0o700,
+			expected: 0o755,
+		},
+		{
+			scenario:      "reject",
+			policy:        ModePolicy{Allowed: 0o775, Reject: true},
+			mode:          os.ModeSetgid | 0o777,
+			expectedError: "/tmp/file: illegal file mode: grwxrwxrwx has forbidden bits g-------w-",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := tc.policy.apply("/tmp/file", tc.mode)
+
+			assert.Equal(t, tc.expected, actual)
+
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedError)
+				require.ErrorIs(t, err, ErrIllegalFileMode)
+			}
+		})
+	}
+}
+
+func TestGzipInstaller_Install_ModePolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		opts          []Option
+		expectedMode  os.FileMode
+		expectedError error
+	}{
+		{
+			scenario:     "sanitise",
+			expectedMode: 0o755,
+		},
+		{
+			scenario:      "reject",
+			opts:          []Option{WithModePolicy(ModePolicy{Allowed: 0o775, Reject: true})},
+			expectedError: ErrIllegalFileMode,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			require.NoError(t, afero.WriteFile(fs, "/tmp/.plugin.registry.yaml", []byte("name: my-plugin"), 0o644))
+			require.NoError(t, afero.WriteFile(fs, "/tmp/my-plugin.tar.gz", newTarGz(t, archiveEntry{
+				name:    "my-plugin/my-plugin",
+				content: "#!/bin/bash\n",
+				mode:    0o4757,
+			}), 0o644))
+
+			i := NewGzipInstaller(fs, tc.opts...)
+
+			_, err := i.Install(context.Background(), "/app/plugins", "/tmp/my-plugin.tar.gz")
+
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			fi, err := fs.Stat("/app/plugins/my-plugin/my-plugin")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedMode, fi.Mode())
+		})
+	}
+}
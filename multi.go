@@ -0,0 +1,281 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrDuplicatePlugin indicates that more than one plugin in the source has the same name.
+var ErrDuplicatePlugin = errors.New("duplicate plugin")
+
+// Source is a plugin found in a source directory.
+type Source struct {
+	// Path is the directory that contains the plugin folder or file.
+	Path   string
+	Plugin *plugin.Plugin
+
+	metadata *metadata
+}
+
+// multiMetadata is a .plugin.registry.yaml that describes several plugins, for example:
+//
+//	plugins:
+//	  - name: my-plugin
+//	  - name: my-other-plugin
+//	    files:
+//	      exclude: [README.md]
+type multiMetadata struct {
+	Plugins []yaml.Node `yaml:"plugins"`
+}
+
+// Plugins lists the plugins in the source directory. The .plugin.registry.yaml of the directory describes either one
+// plugin, or several plugins in a "plugins" list with their folders side by side. When the directory has no
+// metadata, its subfolders are scanned and each of them is read the same way. The sources are sorted by name and
+// version.
+func (i *Installer) Plugins(path string) ([]Source, error) {
+	return findSources(i.srcFs, path)
+}
+
+// InstallAll installs all the plugins in the source directory, or only the ones with the given names. When a plugin is
+// found in several versions, the highest version is installed. The installation stops at the first failure, the
+// plugins that are installed before the failure are returned along with the error.
+func (i *Installer) InstallAll(ctx context.Context, dest, path string, names ...string) ([]*plugin.Plugin, error) {
+	sources, err := findSources(i.srcFs, path)
+	if err != nil {
+		err = installError(ctx, PhaseDetect, path, err, "could not parse plugin path", "path", path)
+
+		return nil, i.observeDetectError(ctx, err)
+	}
+
+	sources, err = selectSources(sources, names)
+	if err != nil {
+		err = installError(ctx, PhaseDetect, path, err, "could not find plugin", "path", path)
+
+		return nil, i.observeDetectError(ctx, err)
+	}
+
+	return i.installSources(ctx, dest, sources)
+}
+
+// installSources installs the sources in order, it stops at the first failure.
+func (i *Installer) installSources(ctx context.Context, dest string, sources []Source) ([]*plugin.Plugin, error) {
+	result := make([]*plugin.Plugin, 0, len(sources))
+
+	for _, s := range sources {
+		s := s
+
+		p, err := i.observeInstall(ctx, func(ctx context.Context) (*plugin.Plugin, error) {
+			i.log(ctx).Debug(ctx, "plugin detected", "installer", "fs", "plugin", s.Plugin.Name, "path", s.Path)
+
+			return s.Plugin, nil
+		}, func(ctx context.Context, p *plugin.Plugin) error {
+			return i.install(ctx, dest, s.Path, p, s.metadata)
+		})
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// selectSources selects the highest version of the plugins with the given names, or of all the plugins if there is no
+// name. The sources must be sorted.
+func selectSources(sources []Source, names []string) ([]Source, error) {
+	byName := make(map[string]Source, len(sources))
+	all := len(names) == 0
+
+	for _, s := range sources {
+		if _, ok := byName[s.Plugin.Name]; !ok && all {
+			names = append(names, s.Plugin.Name)
+		}
+
+		byName[s.Plugin.Name] = s
+	}
+
+	result := make([]Source, 0, len(names))
+
+	for _, name := range names {
+		s, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", name, plugin.ErrPluginNotExist)
+		}
+
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+func findSources(fs afero.Fs, path string) ([]Source, error) {
+	path = filepath.Clean(strings.TrimPrefix(path, "file://"))
+
+	isDir, err := afero.IsDir(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDir {
+		return nil, ErrPluginNotDir
+	}
+
+	if hasMetadata(fs, path) {
+		sources, err := loadSources(fs, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return sortSources(sources)
+	}
+
+	entries, err := afero.ReadDir(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []Source
+
+	for _, e := range entries {
+		subPath := filepath.Join(path, e.Name())
+
+		if !e.IsDir() || !hasMetadata(fs, subPath) {
+			continue
+		}
+
+		found, err := loadSources(fs, subPath)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, found...)
+	}
+
+	return sortSources(sources)
+}
+
+func hasMetadata(fs afero.Fs, path string) bool {
+	_, err := fs.Stat(filepath.Join(path, plugin.MetadataFile))
+
+	return err == nil
+}
+
+// isMultiPlugin checks whether the .plugin.registry.yaml of the directory has a "plugins" list.
+func isMultiPlugin(fs afero.Fs, path string) bool {
+	data, err := afero.ReadFile(fs, filepath.Join(path, plugin.MetadataFile))
+	if err != nil {
+		return false
+	}
+
+	var mm multiMetadata
+
+	return yaml.Unmarshal(data, &mm) == nil && len(mm.Plugins) > 0
+}
+
+// loadSources loads the plugins described by the metadata in the directory.
+func loadSources(fs afero.Fs, path string) ([]Source, error) {
+	data, err := afero.ReadFile(fs, filepath.Join(path, plugin.MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var mm multiMetadata
+
+	if err := yaml.Unmarshal(data, &mm); err != nil {
+		return nil, err
+	}
+
+	if len(mm.Plugins) == 0 {
+		path, p, err := parseFsPlugin(fs, path)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := loadMetadata(fs, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Source{{Path: path, Plugin: p, metadata: m}}, nil
+	}
+
+	sources := make([]Source, 0, len(mm.Plugins))
+
+	for _, n := range mm.Plugins {
+		s, err := decodeSource(fs, path, n)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, s)
+	}
+
+	return sources, nil
+}
+
+func decodeSource(fs afero.Fs, path string, n yaml.Node) (Source, error) {
+	var (
+		p plugin.Plugin
+		m metadata
+	)
+
+	if err := n.Decode(&p); err != nil {
+		return Source{}, err
+	}
+
+	if err := n.Decode(&m); err != nil {
+		return Source{}, err
+	}
+
+	if p.Name == "" {
+		return Source{}, ErrPluginNoName
+	}
+
+	pluginPath := filepath.Join(path, p.Name)
+
+	if _, err := fs.Stat(pluginPath); err != nil {
+		return Source{}, fmt.Errorf("%s: %w", pluginPath, err)
+	}
+
+	return Source{Path: path, Plugin: &p, metadata: &m}, nil
+}
+
+// sortSources sorts the sources by name and version, a plugin can be in several sources with different versions.
+func sortSources(sources []Source) ([]Source, error) {
+	sort.SliceStable(sources, func(i, j int) bool {
+		if sources[i].Plugin.Name != sources[j].Plugin.Name {
+			return sources[i].Plugin.Name < sources[j].Plugin.Name
+		}
+
+		return sourceVersion(sources[i]).LessThan(sourceVersion(sources[j]))
+	})
+
+	for i := 1; i < len(sources); i++ {
+		if sources[i].Plugin.Name == sources[i-1].Plugin.Name &&
+			sourceVersion(sources[i]).Equal(sourceVersion(sources[i-1])) {
+			return nil, fmt.Errorf("%s: %w", sources[i].Plugin.Name, ErrDuplicatePlugin)
+		}
+	}
+
+	return sources, nil
+}
+
+// sourceVersion returns the version of the source, the plugins without a valid version are the lowest.
+func sourceVersion(s Source) *semver.Version {
+	v, err := semver.NewVersion(s.Plugin.Version)
+	if err != nil {
+		return &semver.Version{}
+	}
+
+	return v
+}
@@ -0,0 +1,224 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	fsCtx "github.com/nhatthm/plugin-registry/context"
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMultiPluginFs(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o755))
+	}
+
+	return fs
+}
+
+func pluginNames(plugins []*plugin.Plugin) []string {
+	names := make([]string, 0, len(plugins))
+
+	for _, p := range plugins {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
+
+func TestInstaller_Plugins(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		files         map[string]string
+		expected      []string
+		expectedError string
+	}{
+		{
+			scenario:      "not a directory",
+			files:         map[string]string{"/src": ""},
+			expectedError: "plugin is not a directory",
+		},
+		{
+			scenario: "single plugin",
+			files: map[string]string{
+				"/src/.plugin.registry.yaml": "name: my-plugin",
+				"/src/my-plugin/my-plugin":   "#!/bin/bash\n",
+			},
+			expected: []string{"my-plugin"},
+		},
+		{
+			scenario: "plugins in one metadata",
+			files: map[string]string{
+				"/src/.plugin.registry.yaml": "plugins:\n  - name: plugin-b\n  - name: plugin-a\n",
+				"/src/plugin-a/plugin-a":     "#!/bin/bash\n",
+				"/src/plugin-b/plugin-b":     "#!/bin/bash\n",
+			},
+			expected: []string{"plugin-a", "plugin-b"},
+		},
+		{
+			scenario: "plugin in metadata without name",
+			files: map[string]string{
+				"/src/.plugin.registry.yaml": "plugins:\n  - name: plugin-a\n  - enabled: false\n",
+				"/src/plugin-a/plugin-a":     "#!/bin/bash\n",
+			},
+			expectedError: "plugin has no name",
+		},
+		{
+			scenario: "plugin in metadata without folder",
+			files: map[string]string{
+				"/src/.plugin.registry.yaml": "plugins:\n  - name: plugin-a\n  - name: plugin-b\n",
+				"/src/plugin-a/plugin-a":     "#!/bin/bash\n",
+			},
+			expectedError: "/src/plugin-b: open /src/plugin-b: file does not exist",
+		},
+		{
+			scenario: "scan subfolders",
+			files: map[string]string{
+				"/src/a/.plugin.registry.yaml": "name: plugin-a",
+				"/src/a/plugin-a/plugin-a":     "#!/bin/bash\n",
+				"/src/b/.plugin.registry.yaml": "plugins:\n  - name: plugin-b\n  - name: plugin-c\n",
+				"/src/b/plugin-b/plugin-b":     "#!/bin/bash\n",
+				"/src/b/plugin-c":              "#!/bin/bash\n",
+				"/src/docs/README.md":          "# plugins\n",
+				"/src/README.md":               "# plugins\n",
+			},
+			expected: []string{"plugin-a", "plugin-b", "plugin-c"},
+		},
+		{
+			scenario: "same plugin with different versions",
+			files: map[string]string{
+				"/src/a/.plugin.registry.yaml": "name: my-plugin\nversion: 1.1.0",
+				"/src/a/my-plugin/my-plugin":   "#!/bin/bash\n",
+				"/src/b/.plugin.registry.yaml": "name: my-plugin\nversion: 1.0.0",
+				"/src/b/my-plugin/my-plugin":   "#!/bin/bash\n",
+			},
+			expected: []string{"my-plugin@1.0.0", "my-plugin@1.1.0"},
+		},
+		{
+			scenario: "duplicate plugins",
+			files: map[string]string{
+				"/src/a/.plugin.registry.yaml": "name: my-plugin",
+				"/src/a/my-plugin/my-plugin":   "#!/bin/bash\n",
+				"/src/b/.plugin.registry.yaml": "name: my-plugin",
+				"/src/b/my-plugin/my-plugin":   "#!/bin/bash\n",
+			},
+			expectedError: "my-plugin: duplicate plugin",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			sources, err := NewFsInstaller(newMultiPluginFs(t, tc.files)).Plugins("/src")
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			actual := make([]string, 0, len(sources))
+
+			for _, s := range sources {
+				if s.Plugin.Version == "" {
+					actual = append(actual, s.Plugin.Name)
+				} else {
+					actual = append(actual, s.Plugin.Name+"@"+s.Plugin.Version)
+				}
+			}
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestInstaller_InstallAll(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"/src/.plugin.registry.yaml": "plugins:\n  - name: plugin-a\n  - name: plugin-b\n    files:\n      exclude: [README.md]\n",
+		"/src/plugin-a/plugin-a":     "#!/bin/bash\n",
+		"/src/plugin-a/README.md":    "# plugin-a\n",
+		"/src/plugin-b/plugin-b":     "#!/bin/bash\n",
+		"/src/plugin-b/README.md":    "# plugin-b\n",
+	}
+
+	testCases := []struct {
+		scenario      string
+		names         []string
+		expected      []string
+		expectedError string
+	}{
+		{
+			scenario: "all",
+			expected: []string{"plugin-a", "plugin-b"},
+		},
+		{
+			scenario: "subset",
+			names:    []string{"plugin-b"},
+			expected: []string{"plugin-b"},
+		},
+		{
+			scenario:      "unknown plugin",
+			names:         []string{"plugin-b", "plugin-c"},
+			expectedError: "could not find plugin: plugin-c: plugin does not exist",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := newMultiPluginFs(t, files)
+
+			result, err := NewFsInstaller(fs).InstallAll(context.Background(), "/app/plugins", "/src", tc.names...)
+
+			if tc.expectedError != "" {
+				assert.Nil(t, result)
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, pluginNames(result))
+
+			for _, name := range tc.expected {
+				exists, err := afero.Exists(fs, "/app/plugins/"+name+"/"+name)
+				require.NoError(t, err)
+
+				assert.True(t, exists)
+			}
+
+			exists, err := afero.Exists(fs, "/app/plugins/plugin-b/README.md")
+			require.NoError(t, err)
+
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestIsFsPlugin_MultiPlugin(t *testing.T) {
+	t.Parallel()
+
+	fs := newMultiPluginFs(t, map[string]string{
+		"/src/.plugin.registry.yaml": "plugins:\n  - name: plugin-a\n",
+		"/src/plugin-a/plugin-a":     "#!/bin/bash\n",
+	})
+
+	assert.False(t, isFsPlugin(fsCtx.WithFs(context.Background(), fs), "/src"))
+}
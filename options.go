@@ -0,0 +1,94 @@
+package fs
+
+import (
+	"time"
+
+	"github.com/bool64/ctxd"
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+// Option configures an installer.
+type Option func(c *config)
+
+type config struct {
+	kind string
+
+	srcFs  afero.Fs
+	destFs afero.Fs
+
+	link  bool
+	dedup bool
+
+	// version is the version resolved by InstallVersion or a catalog, it overrides the version in the metadata.
+	version string
+
+	platform        *plugin.ArtifactIdentifier
+	modePolicy      ModePolicy
+	duplicatePolicy DuplicatePolicy
+
+	stripComponents int
+	subPath         string
+	filter          fileFilter
+
+	lockTimeout time.Duration
+
+	logger   ctxd.Logger
+	span     SpanFunc
+	observer Observer
+
+	hookRunner HookRunner
+	hooks      Hooks
+
+	events *events
+}
+
+func newConfig(fs afero.Fs, opts ...Option) config {
+	c := config{
+		srcFs:      fs,
+		destFs:     fs,
+		modePolicy: DefaultModePolicy(),
+		observer:   NopObserver{},
+		events:     &events{},
+	}
+
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return c
+}
+
+// WithSourceFs sets the file system that the plugin is read from. By default, the installers read the plugin from and
+// install it to the file system given to their constructor.
+func WithSourceFs(fs afero.Fs) Option {
+	return func(c *config) {
+		c.srcFs = fs
+	}
+}
+
+// WithDestinationFs sets the file system that the plugin is installed to, see WithSourceFs.
+func WithDestinationFs(fs afero.Fs) Option {
+	return func(c *config) {
+		c.destFs = fs
+	}
+}
+
+// withVersion sets the version of the installed plugin.
+func withVersion(v string) Option {
+	return func(c *config) {
+		c.version = v
+	}
+}
+
+// forPlugin returns the config for installing a plugin with the given metadata.
+func (c config) forPlugin(m *metadata) (*config, error) {
+	c.filter = c.filter.merge(m.Files)
+	c.hooks = m.Hooks
+
+	if err := c.filter.validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConfig(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	srcFs := afero.NewReadOnlyFs(fs)
+	destFs := afero.NewMemMapFs()
+
+	cfg := newConfig(fs)
+
+	assert.Equal(t, fs, cfg.srcFs)
+	assert.Equal(t, fs, cfg.destFs)
+
+	cfg = newConfig(fs, WithSourceFs(srcFs), WithDestinationFs(destFs))
+
+	assert.Equal(t, srcFs, cfg.srcFs)
+	assert.Equal(t, destFs, cfg.destFs)
+}
+
+func TestInstaller_Install_SeparateFs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		path     string
+	}{
+		{
+			scenario: "fs",
+			path:     "resources/fixtures/fs/folder",
+		},
+		{
+			scenario: "zip",
+			path:     "resources/fixtures/zip/my-plugin.zip",
+		},
+		{
+			scenario: "gzip",
+			path:     "resources/fixtures/gzip/my-plugin.tar.gz",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			srcFs := afero.NewReadOnlyFs(afero.NewOsFs())
+			destFs := afero.NewMemMapFs()
+
+			i := newInstaller(t, tc.scenario, srcFs, WithDestinationFs(destFs))
+
+			p, err := i.Install(context.Background(), "/app/plugins", tc.path)
+			require.NoError(t, err)
+
+			data, err := afero.ReadFile(destFs, "/app/plugins/my-plugin/my-plugin")
+			require.NoError(t, err)
+
+			assert.Equal(t, "my-plugin", p.Name)
+			assert.Equal(t, "#!/bin/bash\n", string(data))
+		})
+	}
+}
+
+func TestFsInstaller_Install_MemMapFsSource(t *testing.T) {
+	t.Parallel()
+
+	srcFs := afero.NewMemMapFs()
+	destFs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(srcFs, "/tmp/.plugin.registry.yaml", []byte("name: my-plugin"), 0o644))
+	require.NoError(t, afero.WriteFile(srcFs, "/tmp/my-plugin/my-plugin", []byte("#!/bin/bash\n"), 0o755))
+
+	modTime := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, srcFs.Chtimes("/tmp/my-plugin/my-plugin", modTime, modTime))
+
+	i := NewFsInstaller(srcFs, WithDestinationFs(destFs))
+
+	p, err := i.Install(context.Background(), "/app/plugins", "/tmp")
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(destFs, "/app/plugins/my-plugin/my-plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, "my-plugin", p.Name)
+	assert.Equal(t, "#!/bin/bash\n", string(data))
+
+	fi, err := destFs.Stat("/app/plugins/my-plugin/my-plugin")
+	require.NoError(t, err)
+
+	assert.True(t, modTime.Equal(fi.ModTime()))
+}
@@ -0,0 +1,387 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+var (
+	// ErrUnsupportedFile indicates that a file cannot be packed because it is neither a regular file nor a directory.
+	ErrUnsupportedFile = errors.New("unsupported file type")
+	// ErrUnsupportedFormat indicates that the archive format is not supported.
+	ErrUnsupportedFormat = errors.New("unsupported archive format")
+	// ErrInvalidSourceDateEpoch indicates that the SOURCE_DATE_EPOCH environment variable is not a unix timestamp.
+	ErrInvalidSourceDateEpoch = errors.New("invalid SOURCE_DATE_EPOCH")
+)
+
+// packCompressionLevel is the compression level of the archives, it is fixed so that the archives are reproducible.
+const packCompressionLevel = flate.BestCompression
+
+// defaultModTime is the modification time of the packed files when there is no SOURCE_DATE_EPOCH, it is the earliest
+// time that a zip supports.
+var defaultModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Format is an archive format that the installers support.
+type Format string
+
+const (
+	// FormatZip is a zip archive, installed by the zip installer.
+	FormatZip Format = "zip"
+	// FormatTarGz is a gzip compressed tar archive, installed by the gzip installer.
+	FormatTarGz Format = "tar.gz"
+)
+
+// Package is an archive built by Pack.
+type Package struct {
+	Plugin *plugin.Plugin
+	// Archive is the path of the archive.
+	Archive string
+	// Metadata is the path of the .plugin.registry.yaml next to the archive.
+	Metadata string
+	// Checksum is the hex encoded SHA-256 of the archive.
+	Checksum string
+	// ChecksumFile is the path of the checksum file, in the format of sha256sum.
+	ChecksumFile string
+}
+
+// PackOption configures Pack.
+type PackOption func(c *packConfig)
+
+type packConfig struct {
+	name    string
+	modTime *time.Time
+}
+
+// WithArchiveName sets the file name of the archive. By default, it is the artifact file of the runtime platform in
+// .plugin.registry.yaml if it has the extension of the format, or "<name>-<version>-<os>-<arch>.<format>".
+func WithArchiveName(name string) PackOption {
+	return func(c *packConfig) {
+		c.name = name
+	}
+}
+
+// WithModTime sets the modification time of the packed files and directories. By default, it is the time of the
+// SOURCE_DATE_EPOCH environment variable, see https://reproducible-builds.org/specs/source-date-epoch/, or
+// 1980-01-01 00:00:00 UTC.
+func WithModTime(t time.Time) PackOption {
+	return func(c *packConfig) {
+		t = t.UTC().Truncate(time.Second)
+		c.modTime = &t
+	}
+}
+
+// packEntry is a file or directory to pack.
+type packEntry struct {
+	name string
+	path string
+	mode os.FileMode
+	size int64
+}
+
+// Pack packs the plugin in the source directory, laid out like for the file system installer, into a zip or tar.gz
+// archive in the destination directory. The files are put in a "<name>/" folder, the directories get the mode 0755
+// and the files 0755 if they are executable or 0644 otherwise. The .plugin.registry.yaml of the source and a
+// "<archive>.sha256" checksum file are written next to the archive.
+//
+// The archives are reproducible: the entries are sorted by name, they have the same modification time, see
+// WithModTime, no owner, and are compressed with fixed parameters.
+func Pack(fs afero.Fs, src, dest string, format Format, opts ...PackOption) (*Package, error) {
+	var cfg packConfig
+
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	src, p, err := parseFsPlugin(fs, src)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := cfg.archiveName(p, format)
+	if err != nil {
+		return nil, err
+	}
+
+	modTime, err := cfg.resolveModTime()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := packEntries(fs, filepath.Join(src, p.Name), p.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fs.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+
+	pkg := &Package{
+		Plugin:       p,
+		Archive:      filepath.Join(dest, name),
+		Metadata:     filepath.Join(dest, plugin.MetadataFile),
+		ChecksumFile: filepath.Join(dest, name+".sha256"),
+	}
+
+	if pkg.Checksum, err = writeArchive(fs, pkg.Archive, format, files, modTime); err != nil {
+		return nil, err
+	}
+
+	checksum := fmt.Sprintf("%s  %s\n", pkg.Checksum, name)
+
+	if err := afero.WriteFile(fs, pkg.ChecksumFile, []byte(checksum), 0o644); err != nil {
+		return nil, err
+	}
+
+	if metadata := filepath.Join(src, plugin.MetadataFile); filepath.Clean(metadata) != filepath.Clean(pkg.Metadata) {
+		data, err := afero.ReadFile(fs, metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := afero.WriteFile(fs, pkg.Metadata, data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	return pkg, nil
+}
+
+func (c packConfig) archiveName(p *plugin.Plugin, format Format) (string, error) {
+	ext := "." + string(format)
+
+	switch format {
+	case FormatZip, FormatTarGz:
+	default:
+		return "", fmt.Errorf("%s: %w", format, ErrUnsupportedFormat)
+	}
+
+	if c.name != "" {
+		return c.name, nil
+	}
+
+	if file := p.ResolveArtifact(p.RuntimeArtifact()).File; strings.HasSuffix(file, ext) {
+		return filepath.Base(file), nil
+	}
+
+	return fmt.Sprintf("%s-%s-%s-%s%s", p.Name, p.Version, runtime.GOOS, runtime.GOARCH, ext), nil
+}
+
+func (c packConfig) resolveModTime() (time.Time, error) {
+	if c.modTime != nil {
+		return *c.modTime, nil
+	}
+
+	epoch, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || epoch == "" {
+		return defaultModTime, nil
+	}
+
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidSourceDateEpoch, epoch)
+	}
+
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// packEntries lists the files and directories to pack, the names are slash separated and start with the prefix.
+func packEntries(fs afero.Fs, root, prefix string) ([]packEntry, error) {
+	var result []packEntry
+
+	err := afero.Walk(fs, root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		name := path.Join(prefix, filepath.ToSlash(rel))
+
+		if rel == "." && !fi.IsDir() {
+			// The plugin is a binary file.
+			name = path.Join(prefix, prefix)
+		}
+
+		e := packEntry{name: name, path: p}
+
+		switch {
+		case fi.IsDir():
+			e.mode = os.ModeDir | 0o755
+
+		case fi.Mode().IsRegular():
+			e.mode = 0o644
+			e.size = fi.Size()
+
+			if fi.Mode()&0o111 != 0 {
+				e.mode = 0o755
+			}
+
+		default:
+			return fmt.Errorf("%s: %w", p, ErrUnsupportedFile)
+		}
+
+		result = append(result, e)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 || !result[0].mode.IsDir() {
+		// Add the top level folder of a binary file.
+		result = append(result, packEntry{name: prefix, mode: os.ModeDir | 0o755})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+
+	return result, nil
+}
+
+// writeArchive writes the archive and returns its checksum.
+func writeArchive(fs afero.Fs, dest string, format Format, files []packEntry, modTime time.Time) (_ string, err error) {
+	out, err := fs.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644) //nolint: nosnakecase
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			_ = fs.Remove(dest) //nolint: errcheck
+		}
+	}()
+
+	h := sha256.New()
+	w := io.MultiWriter(out, h)
+
+	if format == FormatZip {
+		err = writeZip(fs, w, files, modTime)
+	} else {
+		err = writeTarGz(fs, w, files, modTime)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeZip(fs afero.Fs, w io.Writer, files []packEntry, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, packCompressionLevel)
+	})
+
+	for _, f := range files {
+		h := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modTime}
+
+		if f.mode.IsDir() {
+			h.Name += "/"
+			h.Method = zip.Store
+		}
+
+		h.SetMode(f.mode)
+
+		fw, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+
+		if !f.mode.IsDir() {
+			if err := copyFileTo(fs, fw, f.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeTarGz(fs afero.Fs, w io.Writer, files []packEntry, modTime time.Time) error {
+	// The gzip header has no name and no modification time.
+	gw, err := gzip.NewWriterLevel(w, packCompressionLevel)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(gw)
+
+	for _, f := range files {
+		// The entries are owned by root, without user and group names.
+		h := &tar.Header{
+			Name:    f.name,
+			Mode:    int64(f.mode.Perm()),
+			Size:    f.size,
+			ModTime: modTime,
+			Uid:     0,
+			Gid:     0,
+			Format:  tar.FormatPAX,
+		}
+
+		h.Typeflag = tar.TypeReg
+
+		if f.mode.IsDir() {
+			h.Name += "/"
+			h.Typeflag = tar.TypeDir
+		}
+
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+
+		if !f.mode.IsDir() {
+			if err := copyFileTo(fs, tw, f.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+func copyFileTo(fs afero.Fs, w io.Writer, path string) error {
+	f, err := fs.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close() //nolint: errcheck
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
@@ -0,0 +1,353 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPackFs(t *testing.T) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	files := []struct {
+		path    string
+		content string
+		mode    os.FileMode
+	}{
+		{path: "/src/.plugin.registry.yaml", content: "name: my-plugin\nversion: 1.2.0\n", mode: 0o644},
+		{path: "/src/my-plugin/my-plugin", content: "#!/bin/bash\n", mode: 0o700},
+		{path: "/src/my-plugin/lib/data.txt", content: "data", mode: 0o666},
+		{path: "/bin-src/.plugin.registry.yaml", content: "name: my-binary\nversion: 2.0.0\n", mode: 0o644},
+		{path: "/bin-src/my-binary", content: "#!/bin/bash\n", mode: 0o755},
+	}
+
+	for _, f := range files {
+		require.NoError(t, afero.WriteFile(fs, f.path, []byte(f.content), f.mode))
+	}
+
+	return fs
+}
+
+func TestPack_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	platform := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+
+	testCases := []struct {
+		scenario        string
+		src             string
+		format          Format
+		expectedArchive string
+		expectedFiles   map[string]os.FileMode
+	}{
+		{
+			scenario:        "zip",
+			src:             "/src",
+			format:          FormatZip,
+			expectedArchive: "/dist/my-plugin-1.2.0-" + platform + ".zip",
+			expectedFiles: map[string]os.FileMode{
+				"/app/plugins/my-plugin/my-plugin":    0o755,
+				"/app/plugins/my-plugin/lib/data.txt": 0o644,
+			},
+		},
+		{
+			scenario:        "tar.gz",
+			src:             "/src",
+			format:          FormatTarGz,
+			expectedArchive: "/dist/my-plugin-1.2.0-" + platform + ".tar.gz",
+			expectedFiles: map[string]os.FileMode{
+				"/app/plugins/my-plugin/my-plugin":    0o755,
+				"/app/plugins/my-plugin/lib/data.txt": 0o644,
+			},
+		},
+		{
+			scenario:        "binary",
+			src:             "/bin-src",
+			format:          FormatTarGz,
+			expectedArchive: "/dist/my-binary-2.0.0-" + platform + ".tar.gz",
+			expectedFiles: map[string]os.FileMode{
+				"/app/plugins/my-binary/my-binary": 0o755,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := newPackFs(t)
+
+			pkg, err := Pack(fs, tc.src, "/dist", tc.format)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedArchive, pkg.Archive)
+			assert.Equal(t, "/dist/.plugin.registry.yaml", pkg.Metadata)
+			assert.Equal(t, tc.expectedArchive+".sha256", pkg.ChecksumFile)
+
+			archive, err := afero.ReadFile(fs, pkg.Archive)
+			require.NoError(t, err)
+
+			sum := sha256.Sum256(archive)
+
+			assert.Equal(t, hex.EncodeToString(sum[:]), pkg.Checksum)
+
+			checksum, err := afero.ReadFile(fs, pkg.ChecksumFile)
+			require.NoError(t, err)
+
+			assert.Equal(t, fmt.Sprintf("%s  %s\n", pkg.Checksum, tc.expectedArchive[len("/dist/"):]), string(checksum))
+
+			i, err := newInstallerFor(fs, pkg.Archive)
+			require.NoError(t, err)
+
+			p, err := i.Install(context.Background(), "/app/plugins", pkg.Archive)
+			require.NoError(t, err)
+			assert.Equal(t, pkg.Plugin.Name, p.Name)
+
+			for path, mode := range tc.expectedFiles {
+				fi, err := fs.Stat(path)
+				require.NoError(t, err, path)
+
+				assert.Equal(t, mode, fi.Mode().Perm(), path)
+			}
+		})
+	}
+}
+
+func TestPack_Error(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		src           string
+		format        Format
+		expectedError string
+	}{
+		{
+			scenario:      "not a plugin",
+			src:           "/unknown",
+			format:        FormatZip,
+			expectedError: "open /unknown: file does not exist",
+		},
+		{
+			scenario:      "unsupported format",
+			src:           "/src",
+			format:        "rar",
+			expectedError: "rar: unsupported archive format",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			pkg, err := Pack(newPackFs(t), tc.src, "/dist", tc.format)
+
+			assert.Nil(t, pkg)
+			require.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestPack_ArchiveName(t *testing.T) {
+	t.Parallel()
+
+	fs := newPackFs(t)
+
+	pkg, err := Pack(fs, "/src", "/src", FormatZip, WithArchiveName("my-plugin.zip"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "/src/my-plugin.zip", pkg.Archive)
+
+	data, err := afero.ReadFile(fs, "/src/.plugin.registry.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "name: my-plugin\nversion: 1.2.0\n", string(data))
+}
+
+func TestPack_Reproducible(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []Format{FormatZip, FormatTarGz} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			first := newPackFs(t)
+			second := afero.NewMemMapFs()
+
+			// Same files, created in another order, with other times and permissions.
+			files := []struct {
+				path    string
+				content string
+				mode    os.FileMode
+			}{
+				{path: "/src/my-plugin/lib/data.txt", content: "data", mode: 0o600},
+				{path: "/src/my-plugin/my-plugin", content: "#!/bin/bash\n", mode: 0o711},
+				{path: "/src/.plugin.registry.yaml", content: "name: my-plugin\nversion: 1.2.0\n", mode: 0o600},
+			}
+
+			for _, f := range files {
+				require.NoError(t, afero.WriteFile(second, f.path, []byte(f.content), f.mode))
+				require.NoError(t, second.Chtimes(f.path, time.Now(), time.Now().Add(-time.Hour)))
+			}
+
+			opt := WithModTime(time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC))
+
+			pkg1, err := Pack(first, "/src", "/dist", format, opt)
+			require.NoError(t, err)
+
+			pkg2, err := Pack(second, "/src", "/dist", format, opt)
+			require.NoError(t, err)
+
+			pkg3, err := Pack(first, "/src", "/dist", format, opt)
+			require.NoError(t, err)
+
+			assert.Equal(t, pkg1.Checksum, pkg2.Checksum)
+			assert.Equal(t, pkg1.Checksum, pkg3.Checksum)
+
+			archive1, err := afero.ReadFile(first, pkg1.Archive)
+			require.NoError(t, err)
+
+			archive2, err := afero.ReadFile(second, pkg2.Archive)
+			require.NoError(t, err)
+
+			assert.Equal(t, archive1, archive2)
+		})
+	}
+}
+
+func TestPack_Headers(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+	expectedNames := []string{"my-plugin/", "my-plugin/lib/", "my-plugin/lib/data.txt", "my-plugin/my-plugin"}
+
+	fs := newPackFs(t)
+
+	tarPkg, err := Pack(fs, "/src", "/dist", FormatTarGz, WithModTime(modTime.Add(time.Millisecond)))
+	require.NoError(t, err)
+
+	f, err := fs.Open(tarPkg.Archive)
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	assert.Empty(t, gr.Name)
+	assert.True(t, gr.ModTime.IsZero())
+
+	tr := tar.NewReader(gr)
+	names := make([]string, 0, len(expectedNames))
+
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+
+		names = append(names, h.Name)
+
+		assert.True(t, modTime.Equal(h.ModTime), h.Name)
+		assert.Equal(t, 0, h.Uid, h.Name)
+		assert.Equal(t, 0, h.Gid, h.Name)
+		assert.Empty(t, h.Uname, h.Name)
+		assert.Empty(t, h.Gname, h.Name)
+	}
+
+	assert.Equal(t, expectedNames, names)
+
+	zipPkg, err := Pack(fs, "/src", "/dist", FormatZip, WithModTime(modTime))
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, zipPkg.Archive)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	names = names[:0]
+
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+
+		assert.True(t, modTime.Equal(f.Modified), f.Name)
+	}
+
+	assert.Equal(t, expectedNames, names)
+}
+
+func TestPack_SourceDateEpoch(t *testing.T) { //nolint: paralleltest // t.Setenv does not work with parallel tests.
+	testCases := []struct {
+		scenario      string
+		epoch         string
+		expected      time.Time
+		expectedError string
+	}{
+		{
+			scenario: "not set",
+			expected: time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			scenario: "set",
+			epoch:    "1622548800",
+			expected: time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			scenario:      "invalid",
+			epoch:         "yesterday",
+			expectedError: "invalid SOURCE_DATE_EPOCH: yesterday",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tc.epoch)
+
+			fs := newPackFs(t)
+
+			pkg, err := Pack(fs, "/src", "/dist", FormatZip)
+
+			if tc.expectedError != "" {
+				assert.Nil(t, pkg)
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			data, err := afero.ReadFile(fs, pkg.Archive)
+			require.NoError(t, err)
+
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+
+			for _, f := range zr.File {
+				assert.True(t, tc.expected.Equal(f.Modified), f.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package fs
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+var (
+	// ErrPlatformMismatch indicates that the plugin binary is built for another platform.
+	ErrPlatformMismatch = errors.New("plugin binary does not match the target platform")
+	// ErrUnknownBinaryFormat indicates that the plugin entrypoint is neither a known binary nor a script.
+	ErrUnknownBinaryFormat = errors.New("unknown binary format")
+)
+
+// elfOS is the list of operating systems that use ELF binaries.
+var elfOS = map[string]bool{
+	"android":   true,
+	"dragonfly": true,
+	"freebsd":   true,
+	"illumos":   true,
+	"linux":     true,
+	"netbsd":    true,
+	"openbsd":   true,
+	"solaris":   true,
+}
+
+var elfOSABI = map[elf.OSABI]string{
+	elf.ELFOSABI_LINUX:   "linux",
+	elf.ELFOSABI_FREEBSD: "freebsd",
+	elf.ELFOSABI_NETBSD:  "netbsd",
+	elf.ELFOSABI_OPENBSD: "openbsd",
+	elf.ELFOSABI_SOLARIS: "solaris",
+}
+
+var machoArch = map[macho.Cpu]string{
+	macho.Cpu386:   "386",
+	macho.CpuAmd64: "amd64",
+	macho.CpuArm:   "arm",
+	macho.CpuArm64: "arm64",
+}
+
+var peArch = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "arm",
+	pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+}
+
+// WithPlatformCheck makes the installers reject the plugin when its entrypoint is a binary built for another platform
+// than the target, for example plugin.RuntimeArtifactIdentifier(). Scripts starting with a shebang are always
+// accepted. When the target has no arch, only the operating system is checked.
+func WithPlatformCheck(target plugin.ArtifactIdentifier) Option {
+	return func(c *config) {
+		c.platform = &target
+	}
+}
+
+// validatePlatform checks whether the plugin entrypoint at <dest>/<name>/<name> is built for the target platform.
+func validatePlatform(fs afero.Fs, dest string, p *plugin.Plugin, target plugin.ArtifactIdentifier) error {
+	path := filepath.Join(dest, p.Name, p.Name)
+
+	f, err := fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint: errcheck
+
+	magic := make([]byte, 4)
+
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return fmt.Errorf("%s: %w", path, ErrUnknownBinaryFormat)
+	}
+
+	if bytes.HasPrefix(magic, []byte("#!")) {
+		return nil
+	}
+
+	platforms, err := binaryPlatforms(f, magic)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, actual := range platforms {
+		if matchPlatform(actual, target) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w: %s is built for %s", path, ErrPlatformMismatch, p.Name, platformString(platforms[0]))
+}
+
+func platformString(id plugin.ArtifactIdentifier) string {
+	if id.OS == "" {
+		id.OS = "elf"
+	}
+
+	return id.String()
+}
+
+// binaryPlatforms detects the platforms of a binary from its header. The os of an ELF binary is empty when the binary
+// does not specify its OS ABI, a Mach-O universal binary has one platform per architecture.
+func binaryPlatforms(r io.ReaderAt, magic []byte) ([]plugin.ArtifactIdentifier, error) {
+	switch {
+	case bytes.Equal(magic, []byte(elf.ELFMAG)):
+		return elfPlatforms(r)
+
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		return pePlatforms(r)
+	}
+
+	if fat, err := macho.NewFatFile(r); err == nil {
+		platforms := make([]plugin.ArtifactIdentifier, 0, len(fat.Arches))
+
+		for _, a := range fat.Arches {
+			platforms = append(platforms, plugin.ArtifactIdentifier{OS: "darwin", Arch: machoArch[a.Cpu]})
+		}
+
+		return platforms, nil
+	}
+
+	if f, err := macho.NewFile(r); err == nil {
+		return []plugin.ArtifactIdentifier{{OS: "darwin", Arch: machoArch[f.Cpu]}}, nil
+	}
+
+	return nil, ErrUnknownBinaryFormat
+}
+
+func elfPlatforms(r io.ReaderAt) ([]plugin.ArtifactIdentifier, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return []plugin.ArtifactIdentifier{{OS: elfOSABI[f.OSABI], Arch: elfArch(f)}}, nil
+}
+
+func elfArch(f *elf.File) string {
+	switch f.Machine { //nolint: exhaustive
+	case elf.EM_386:
+		return "386"
+
+	case elf.EM_X86_64:
+		return "amd64"
+
+	case elf.EM_ARM:
+		return "arm"
+
+	case elf.EM_AARCH64:
+		return "arm64"
+
+	case elf.EM_PPC64:
+		if f.ByteOrder == binary.LittleEndian {
+			return "ppc64le"
+		}
+
+		return "ppc64"
+
+	case elf.EM_RISCV:
+		return "riscv64"
+
+	case elf.EM_S390:
+		return "s390x"
+	}
+
+	return ""
+}
+
+func pePlatforms(r io.ReaderAt) ([]plugin.ArtifactIdentifier, error) {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return []plugin.ArtifactIdentifier{{OS: "windows", Arch: peArch[f.Machine]}}, nil
+}
+
+func matchPlatform(actual, target plugin.ArtifactIdentifier) bool {
+	switch {
+	case actual.OS == "":
+		// ELF binaries without OS ABI run on any ELF operating system.
+		if !elfOS[target.OS] {
+			return false
+		}
+
+	case actual.OS != target.OS:
+		return false
+	}
+
+	return target.Arch == "" || actual.Arch == target.Arch
+}
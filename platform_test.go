@@ -0,0 +1,185 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"testing"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newELFHeader(osABI elf.OSABI, machine elf.Machine) []byte {
+	buf := new(bytes.Buffer)
+
+	ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT), byte(osABI)}
+
+	_ = binary.Write(buf, binary.LittleEndian, elf.Header64{ //nolint: errcheck
+		Ident:     ident,
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Ehsize:    64,
+		Phentsize: 56,
+		Shentsize: 64,
+	})
+
+	return buf.Bytes()
+}
+
+func newMachOHeader(cpu macho.Cpu) []byte {
+	buf := new(bytes.Buffer)
+
+	_ = binary.Write(buf, binary.LittleEndian, macho.FileHeader{ //nolint: errcheck
+		Magic: macho.Magic64,
+		Cpu:   cpu,
+		Type:  macho.TypeExec,
+	})
+
+	// Reserved.
+	_ = binary.Write(buf, binary.LittleEndian, uint32(0)) //nolint: errcheck
+
+	return buf.Bytes()
+}
+
+func newPEHeader(machine uint16) []byte {
+	buf := new(bytes.Buffer)
+
+	dos := make([]byte, 0x40)
+	dos[0], dos[1] = 'M', 'Z'
+	binary.LittleEndian.PutUint32(dos[0x3c:], 0x40)
+
+	_, _ = buf.Write(dos)                                                       //nolint: errcheck
+	_, _ = buf.Write([]byte("PE\x00\x00"))                                      //nolint: errcheck
+	_ = binary.Write(buf, binary.LittleEndian, pe.FileHeader{Machine: machine}) //nolint: errcheck
+
+	return buf.Bytes()
+}
+
+func TestValidatePlatform(t *testing.T) {
+	t.Parallel()
+
+	linuxAmd64 := plugin.ArtifactIdentifier{OS: "linux", Arch: "amd64"}
+
+	testCases := []struct {
+		scenario      string
+		content       []byte
+		target        plugin.ArtifactIdentifier
+		expectedError string
+	}{
+		{
+			scenario: "script",
+			content:  []byte("#!/bin/bash\n"),
+			target:   linuxAmd64,
+		},
+		{
+			scenario:      "empty file",
+			target:        linuxAmd64,
+			expectedError: "/app/plugins/my-plugin/my-plugin: unknown binary format",
+		},
+		{
+			scenario:      "unknown format",
+			content:       []byte("hello world"),
+			target:        linuxAmd64,
+			expectedError: "/app/plugins/my-plugin/my-plugin: unknown binary format",
+		},
+		{
+			scenario: "elf without os abi",
+			content:  newELFHeader(elf.ELFOSABI_NONE, elf.EM_X86_64),
+			target:   linuxAmd64,
+		},
+		{
+			scenario: "elf without arch in target",
+			content:  newELFHeader(elf.ELFOSABI_NONE, elf.EM_AARCH64),
+			target:   plugin.ArtifactIdentifier{OS: "linux"},
+		},
+		{
+			scenario:      "elf with another os abi",
+			content:       newELFHeader(elf.ELFOSABI_FREEBSD, elf.EM_X86_64),
+			target:        linuxAmd64,
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin binary does not match the target platform: my-plugin is built for freebsd/amd64",
+		},
+		{
+			scenario:      "elf with another arch",
+			content:       newELFHeader(elf.ELFOSABI_NONE, elf.EM_AARCH64),
+			target:        linuxAmd64,
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin binary does not match the target platform: my-plugin is built for elf/arm64",
+		},
+		{
+			scenario:      "elf on darwin",
+			content:       newELFHeader(elf.ELFOSABI_NONE, elf.EM_X86_64),
+			target:        plugin.ArtifactIdentifier{OS: "darwin", Arch: "amd64"},
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin binary does not match the target platform: my-plugin is built for elf/amd64",
+		},
+		{
+			scenario: "macho",
+			content:  newMachOHeader(macho.CpuArm64),
+			target:   plugin.ArtifactIdentifier{OS: "darwin", Arch: "arm64"},
+		},
+		{
+			scenario:      "macho on linux",
+			content:       newMachOHeader(macho.CpuAmd64),
+			target:        linuxAmd64,
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin binary does not match the target platform: my-plugin is built for darwin/amd64",
+		},
+		{
+			scenario: "pe",
+			content:  newPEHeader(pe.IMAGE_FILE_MACHINE_AMD64),
+			target:   plugin.ArtifactIdentifier{OS: "windows", Arch: "amd64"},
+		},
+		{
+			scenario:      "pe on linux",
+			content:       newPEHeader(pe.IMAGE_FILE_MACHINE_I386),
+			target:        linuxAmd64,
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin binary does not match the target platform: my-plugin is built for windows/386",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			require.NoError(t, afero.WriteFile(fs, "/app/plugins/my-plugin/my-plugin", tc.content, 0o755))
+
+			err := validatePlatform(fs, "/app/plugins", &plugin.Plugin{Name: "my-plugin"}, tc.target)
+
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestFsInstaller_Install_PlatformMismatch(t *testing.T) {
+	t.Parallel()
+
+	destFs := afero.NewMemMapFs()
+	srcFs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(srcFs, "/tmp/.plugin.registry.yaml", []byte("name: my-plugin"), 0o644))
+	require.NoError(t, afero.WriteFile(srcFs, "/tmp/my-plugin/my-plugin", newPEHeader(pe.IMAGE_FILE_MACHINE_AMD64), 0o755))
+
+	i := NewFsInstaller(srcFs,
+		WithDestinationFs(destFs),
+		WithPlatformCheck(plugin.ArtifactIdentifier{OS: "linux", Arch: "amd64"}),
+	)
+
+	result, err := i.Install(context.Background(), "/app/plugins", "/tmp")
+
+	assert.Nil(t, result)
+	require.ErrorIs(t, err, ErrPlatformMismatch)
+
+	_, err = destFs.Stat("/app/plugins/my-plugin")
+	require.Error(t, err)
+}
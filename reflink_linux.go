@@ -0,0 +1,45 @@
+//go:build linux
+// +build linux
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl request, see ioctl_ficlone(2).
+const ficlone = 0x40049409
+
+// reflink clones the source file to the destination using a copy-on-write reflink.
+func reflink(src, dest string, fi os.FileInfo) (err error) {
+	s, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer s.Close() //nolint: errcheck
+
+	d, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fi.Mode().Perm()) //nolint: nosnakecase
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = d.Close() //nolint: errcheck
+
+		if err != nil {
+			_ = os.Remove(dest) //nolint: errcheck
+		}
+	}()
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, d.Fd(), ficlone, s.Fd()); errno != 0 {
+		return errno
+	}
+
+	if err := d.Chmod(fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(dest, fi.ModTime(), fi.ModTime())
+}
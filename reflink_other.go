@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package fs
+
+import (
+	"errors"
+	"os"
+)
+
+var errReflinkNotSupported = errors.New("reflink is not supported")
+
+// reflink is not supported on this platform.
+func reflink(string, string, os.FileInfo) error {
+	return errReflinkNotSupported
+}
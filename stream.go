@@ -3,18 +3,40 @@ package fs
 import (
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/afero"
 )
 
-func installStream(fs afero.Fs, dest string, src io.Reader, mode os.FileMode) error {
-	out, err := fs.OpenFile(dest, os.O_CREATE|os.O_RDWR, mode) //nolint: nosnakecase
+// installStream writes the stream to a temporary file next to the destination, and then renames it to the destination
+// so that an existing file is replaced instead of partially overwritten.
+func installStream(fs afero.Fs, dest string, src io.Reader, mode os.FileMode) (err error) {
+	tmp := filepath.Join(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp")
+
+	out, err := fs.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode) //nolint: nosnakecase
 	if err != nil {
 		return err
 	}
-	defer out.Close() //nolint: errcheck
+
+	defer func() {
+		if err != nil {
+			_ = fs.Remove(tmp) //nolint: errcheck
+		}
+	}()
 
 	_, err = io.Copy(out, src)
 
-	return err
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = fs.Rename(tmp, dest); err != nil {
+		return &InstallError{Phase: PhaseFinalize, Err: err}
+	}
+
+	return nil
 }
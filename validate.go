@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+var (
+	// ErrEntrypointMissing indicates that the plugin has no entrypoint.
+	ErrEntrypointMissing = errors.New("plugin entrypoint does not exist")
+	// ErrEntrypointNotRegular indicates that the plugin entrypoint is not a regular file.
+	ErrEntrypointNotRegular = errors.New("plugin entrypoint is not a regular file")
+	// ErrEntrypointNotExecutable indicates that the plugin entrypoint is not executable.
+	ErrEntrypointNotExecutable = errors.New("plugin entrypoint is not executable")
+)
+
+// Verify checks that the plugin is installed in the destination with a valid entrypoint, which matches the platform of
+// WithPlatformCheck if it is set.
+func (c *config) Verify(dest, name string) error {
+	if _, err := c.destFs.Stat(filepath.Join(dest, name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", name, ErrPluginNotInstalled)
+		}
+
+		return err
+	}
+
+	return c.validate(dest, &plugin.Plugin{Name: name})
+}
+
+// validate checks the installed plugin.
+func (c *config) validate(dest string, p *plugin.Plugin) error {
+	if err := validateEntrypoint(c.destFs, dest, p); err != nil {
+		return err
+	}
+
+	if c.platform != nil {
+		return validatePlatform(c.destFs, dest, p, *c.platform)
+	}
+
+	return nil
+}
+
+// validateEntrypoint checks whether the installed plugin has its entrypoint at <dest>/<name>/<name>, and the
+// entrypoint is an executable regular file.
+func validateEntrypoint(fs afero.Fs, dest string, p *plugin.Plugin) error {
+	path := filepath.Join(dest, p.Name, p.Name)
+
+	fi, err := fs.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", path, ErrEntrypointMissing)
+		}
+
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s: %w", path, ErrEntrypointNotRegular)
+	}
+
+	// Windows does not have the execute bit.
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("%s: %w", path, ErrEntrypointNotExecutable)
+	}
+
+	return nil
+}
+
+// rollback removes the staged plugin, the installed one is kept with its install record. The record is removed when
+// there is no installed plugin, so it never describes a plugin that is not there.
+func rollback(fs afero.Fs, dest string, p *plugin.Plugin) {
+	_ = fs.RemoveAll(stagingPath(dest, p.Name)) //nolint: errcheck
+
+	if installed, err := lexists(fs, filepath.Join(dest, p.Name)); err == nil && !installed {
+		_ = fs.Remove(recordPath(dest, p.Name)) //nolint: errcheck
+	}
+}
@@ -0,0 +1,274 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEntrypoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		setup         func(fs afero.Fs)
+		expectedError string
+	}{
+		{
+			scenario:      "entrypoint does not exist",
+			setup:         func(afero.Fs) {},
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin entrypoint does not exist",
+		},
+		{
+			scenario: "entrypoint is a directory",
+			setup: func(fs afero.Fs) {
+				_ = fs.MkdirAll("/app/plugins/my-plugin/my-plugin", 0o755) //nolint: errcheck
+			},
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin entrypoint is not a regular file",
+		},
+		{
+			scenario: "entrypoint is not executable",
+			setup: func(fs afero.Fs) {
+				_ = afero.WriteFile(fs, "/app/plugins/my-plugin/my-plugin", nil, 0o644) //nolint: errcheck
+			},
+			expectedError: "/app/plugins/my-plugin/my-plugin: plugin entrypoint is not executable",
+		},
+		{
+			scenario: "success",
+			setup: func(fs afero.Fs) {
+				_ = afero.WriteFile(fs, "/app/plugins/my-plugin/my-plugin", nil, 0o755) //nolint: errcheck
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			tc.setup(fs)
+
+			err := validateEntrypoint(fs, "/app/plugins", &plugin.Plugin{Name: "my-plugin"})
+
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestArchiveInstaller_Install_NoEntrypoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario  string
+		installer func(fs afero.Fs, opts ...Option) *ArchiveInstaller
+		path      string
+	}{
+		{
+			scenario:  "zip",
+			installer: NewZipInstaller,
+			path:      "resources/fixtures/zip/my-plugin-empty.zip",
+		},
+		{
+			scenario:  "gzip",
+			installer: NewGzipInstaller,
+			path:      "resources/fixtures/gzip/my-plugin-empty.tar.gz",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			destFs := afero.NewMemMapFs()
+			i := tc.installer(afero.NewReadOnlyFs(afero.NewOsFs()), WithDestinationFs(destFs))
+
+			result, err := i.Install(context.Background(), "/app/plugins", tc.path)
+
+			assert.Nil(t, result)
+			require.ErrorIs(t, err, ErrEntrypointMissing)
+			assert.Equal(t, "invalid_plugin", ErrorKind(err))
+
+			// The installed files are rolled back.
+			_, err = destFs.Stat("/app/plugins/my-plugin")
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestInstaller_Verify(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		name          string
+		expectedError string
+	}{
+		{
+			scenario: "valid",
+			name:     "my-plugin",
+		},
+		{
+			scenario:      "not installed",
+			name:          "unknown",
+			expectedError: "unknown: plugin is not installed",
+		},
+		{
+			scenario:      "not executable",
+			name:          "my-other-plugin",
+			expectedError: "/app/plugins/my-other-plugin/my-other-plugin: plugin entrypoint is not executable",
+		},
+	}
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "/app/plugins/my-plugin/my-plugin", []byte("#!/bin/bash\n"), 0o755))
+	require.NoError(t, afero.WriteFile(fs, "/app/plugins/my-other-plugin/my-other-plugin", []byte("#!/bin/bash\n"), 0o644))
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			err := NewFsInstaller(fs).Verify("/app/plugins", tc.name)
+
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestInstaller_Install_KeepsInstalledPlugin(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		entrypoint    os.FileMode
+		hookRunner    HookRunner
+		failRecord    bool
+		expectedError string
+	}{
+		{
+			scenario:      "not executable",
+			entrypoint:    0o644,
+			expectedError: "could not validate plugin: /app/plugins/my-plugin/my-plugin: plugin entrypoint is not executable",
+		},
+		{
+			scenario:      "post-install hook fails",
+			entrypoint:    0o755,
+			hookRunner:    &hookRunnerStub{fail: PostInstall},
+			expectedError: `could not run hook: post-install hook "./my-plugin" failed: exit status 1: something went wrong`,
+		},
+		{
+			scenario:      "install record could not be saved",
+			entrypoint:    0o755,
+			failRecord:    true,
+			expectedError: "could not save install record: could not write install record",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			require.NoError(t, afero.WriteFile(fs, "/v1/.plugin.registry.yaml", []byte("name: my-plugin\nversion: 1.0.0\n"), 0o644))
+			require.NoError(t, afero.WriteFile(fs, "/v1/my-plugin/my-plugin", []byte("v1"), 0o755))
+			require.NoError(t, afero.WriteFile(fs, "/v2/.plugin.registry.yaml", []byte("name: my-plugin\nversion: 2.0.0\nhooks:\n  post-install:\n    - command: ./my-plugin\n"), 0o644))
+			require.NoError(t, afero.WriteFile(fs, "/v2/my-plugin/my-plugin", []byte("v2"), tc.entrypoint))
+
+			_, err := NewFsInstaller(fs).Install(context.Background(), "/app/plugins", "/v1")
+			require.NoError(t, err)
+
+			var opts []Option
+
+			if tc.hookRunner != nil {
+				opts = append(opts, WithHookRunner(tc.hookRunner))
+			}
+
+			var installFs afero.Fs = fs
+
+			if tc.failRecord {
+				installFs = &recordFailingFs{Fs: fs}
+			}
+
+			result, err := NewFsInstaller(installFs, opts...).Install(context.Background(), "/app/plugins", "/v2")
+
+			assert.Nil(t, result)
+			require.EqualError(t, err, tc.expectedError)
+
+			content, err := afero.ReadFile(fs, "/app/plugins/my-plugin/my-plugin")
+			require.NoError(t, err)
+
+			assert.Equal(t, "v1", string(content))
+
+			for _, path := range []string{"/app/plugins/.my-plugin.staging", "/app/plugins/.my-plugin.backup"} {
+				_, err = fs.Stat(path)
+				assert.True(t, os.IsNotExist(err), path)
+			}
+
+			plugins, err := NewFsInstaller(fs).List(context.Background(), "/app/plugins")
+			require.NoError(t, err)
+			require.Len(t, plugins, 1)
+
+			assert.Equal(t, "1.0.0", plugins[0].Version)
+			assert.Equal(t, StatusInstalled, plugins[0].Status)
+		})
+	}
+}
+
+func TestInstaller_Install_ReplacesInstalledPlugin(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	for _, v := range []string{"v1", "v2"} {
+		require.NoError(t, afero.WriteFile(fs, "/"+v+"/.plugin.registry.yaml", []byte("name: my-plugin\n"), 0o644))
+		require.NoError(t, afero.WriteFile(fs, "/"+v+"/my-plugin/my-plugin", []byte(v), 0o755))
+		require.NoError(t, afero.WriteFile(fs, "/"+v+"/my-plugin/"+v+".txt", []byte(v), 0o644))
+
+		_, err := NewFsInstaller(fs).Install(context.Background(), "/app/plugins", "/"+v)
+		require.NoError(t, err)
+	}
+
+	content, err := afero.ReadFile(fs, "/app/plugins/my-plugin/my-plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, "v2", string(content))
+
+	for _, path := range []string{"/app/plugins/my-plugin/v1.txt", "/app/plugins/.my-plugin.staging", "/app/plugins/.my-plugin.backup"} {
+		_, err = fs.Stat(path)
+		assert.True(t, os.IsNotExist(err), path)
+	}
+}
+
+// recordFailingFs fails to write the install records.
+type recordFailingFs struct {
+	afero.Fs
+}
+
+func (fs *recordFailingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if strings.Contains(name, ".install.yaml") {
+		return nil, errors.New("could not write install record")
+	}
+
+	return fs.Fs.OpenFile(name, flag, perm)
+}
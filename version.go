@@ -0,0 +1,150 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/nhatthm/plugin-registry/plugin"
+	"github.com/spf13/afero"
+)
+
+var (
+	// ErrNoMatchingVersion indicates that no version satisfies the constraint.
+	ErrNoMatchingVersion = errors.New("no matching version")
+	// ErrNoVersionInTemplate indicates that the artifact file template has no ${version} placeholder.
+	ErrNoVersionInTemplate = errors.New("artifact file template has no ${version}")
+)
+
+const (
+	// latestVersion is the constraint that matches the latest stable version.
+	latestVersion = "latest"
+
+	versionPlaceholder = "${version}"
+)
+
+// InstallVersion installs the highest version of a plugin that satisfies the semver constraint, for example "^1.2" or
+// ">=1.3 <2", from a directory of versioned archives:
+//
+//	./my-project/
+//	├── .plugin.registry.yaml
+//	├── my-plugin-1.2.0-linux-amd64.tar.gz
+//	└── my-plugin-1.3.1-linux-amd64.tar.gz
+//
+// The versions are parsed from the file names using the artifact file template of the runtime platform in
+// .plugin.registry.yaml. The constraint "latest" or an empty constraint installs the latest stable version.
+func InstallVersion(ctx context.Context, fs afero.Fs, dest, dir, constraint string, opts ...Option) (*plugin.Plugin, error) {
+	path, v, err := ResolveArtifact(fs, dir, constraint)
+	if err != nil {
+		return nil, installError(ctx, PhaseDetect, dir, err, "could not resolve plugin version", "path", dir, "constraint", constraint)
+	}
+
+	i, err := newArchiveInstallerFor(fs, path, append([]Option{withVersion(v.Original())}, opts...)...)
+	if err != nil {
+		return nil, installError(ctx, PhaseDetect, path, err, "could not find plugin artifact", "path", path)
+	}
+
+	return i.Install(ctx, dest, path)
+}
+
+// ResolveArtifact finds the archive of the highest version that satisfies the semver constraint in a directory of
+// versioned archives. See InstallVersion.
+func ResolveArtifact(fs afero.Fs, dir, constraint string) (string, *semver.Version, error) {
+	p, err := plugin.Load(fs, dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pattern, err := artifactPattern(p)
+	if err != nil {
+		return "", nil, err
+	}
+
+	files, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		names    []string
+		versions []*semver.Version
+	)
+
+	for _, f := range files {
+		m := pattern.FindStringSubmatch(f.Name())
+		if m == nil || f.IsDir() {
+			continue
+		}
+
+		v, err := semver.NewVersion(m[1])
+		if err != nil {
+			continue
+		}
+
+		names = append(names, f.Name())
+		versions = append(versions, v)
+	}
+
+	found, err := resolveVersion(versions, constraint)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return filepath.Join(dir, names[found]), versions[found], nil
+}
+
+// artifactPattern converts the artifact file template of the runtime platform to a pattern that captures the version.
+func artifactPattern(p *plugin.Plugin) (*regexp.Regexp, error) {
+	// Keep the ${version} placeholder while resolving the others.
+	tpl := *p
+	tpl.Version = versionPlaceholder
+
+	file := tpl.ResolveArtifact(tpl.RuntimeArtifact()).File
+
+	if !strings.Contains(file, versionPlaceholder) {
+		return nil, fmt.Errorf("%s: %w", file, ErrNoVersionInTemplate)
+	}
+
+	parts := strings.Split(file, versionPlaceholder)
+
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	return regexp.Compile("^" + strings.Join(parts, "(.+)") + "$")
+}
+
+// parseConstraint parses a semver constraint, "latest" and an empty constraint match the latest stable version.
+func parseConstraint(constraint string) (*semver.Constraints, error) {
+	if constraint == "" || constraint == latestVersion {
+		constraint = "*"
+	}
+
+	return semver.NewConstraint(constraint)
+}
+
+// resolveVersion returns the index of the highest version that satisfies the constraint.
+func resolveVersion(versions []*semver.Version, constraint string) (int, error) {
+	c, err := parseConstraint(constraint)
+	if err != nil {
+		return -1, err
+	}
+
+	found := -1
+
+	for i, v := range versions {
+		if c.Check(v) && (found < 0 || v.GreaterThan(versions[found])) {
+			found = i
+		}
+	}
+
+	if found < 0 {
+		return -1, fmt.Errorf("%s: %w", constraint, ErrNoMatchingVersion)
+	}
+
+	return found, nil
+}
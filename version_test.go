@@ -0,0 +1,200 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveVersion(t *testing.T) {
+	t.Parallel()
+
+	versions := []*semver.Version{
+		semver.MustParse("1.2.0"),
+		semver.MustParse("2.0.0-beta.1"),
+		semver.MustParse("1.3.1"),
+		semver.MustParse("1.1.0"),
+		semver.MustParse("2.1.0"),
+	}
+
+	testCases := []struct {
+		scenario      string
+		constraint    string
+		expected      string
+		expectedError string
+	}{
+		{scenario: "empty", expected: "2.1.0"},
+		{scenario: "latest", constraint: "latest", expected: "2.1.0"},
+		{scenario: "caret", constraint: "^1.2", expected: "1.3.1"},
+		{scenario: "range", constraint: ">=1.1 <1.3", expected: "1.2.0"},
+		{scenario: "prerelease", constraint: ">=2.0.0-beta.0", expected: "2.1.0"},
+		{scenario: "exact prerelease", constraint: "2.0.0-beta.1", expected: "2.0.0-beta.1"},
+		{scenario: "exact", constraint: "1.1.0", expected: "1.1.0"},
+		{
+			scenario:      "no match",
+			constraint:    "^3",
+			expectedError: "^3: no matching version",
+		},
+		{
+			scenario:      "invalid constraint",
+			constraint:    "not a version",
+			expectedError: "improper constraint: not a version",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			found, err := resolveVersion(versions, tc.constraint)
+
+			if tc.expectedError != "" {
+				assert.Equal(t, -1, found)
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, versions[found].String())
+		})
+	}
+}
+
+func newVersionsFs(t *testing.T, metadata string, versions ...string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "/src/.plugin.registry.yaml", []byte(metadata), 0o644))
+
+	for _, v := range versions {
+		name := fmt.Sprintf("/src/my-plugin-%s-%s-%s.tar.gz", v, runtime.GOOS, runtime.GOARCH)
+		content := newTarGz(t, archiveEntry{name: "my-plugin/my-plugin", content: "#!/bin/bash\n# " + v + "\n"})
+
+		require.NoError(t, afero.WriteFile(fs, name, content, 0o644))
+	}
+
+	// Another platform.
+	require.NoError(t, afero.WriteFile(fs, "/src/my-plugin-9.9.9-plan9-mips.tar.gz", nil, 0o644))
+
+	return fs
+}
+
+func TestResolveArtifact(t *testing.T) {
+	t.Parallel()
+
+	platform := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+
+	testCases := []struct {
+		scenario        string
+		metadata        string
+		constraint      string
+		expectedPath    string
+		expectedVersion string
+		expectedError   string
+	}{
+		{
+			scenario:      "no metadata",
+			expectedError: "could not read metadata: open /src/.plugin.registry.yaml: file does not exist",
+		},
+		{
+			scenario:      "no version in template",
+			metadata:      fmt.Sprintf("name: my-plugin\nartifacts:\n  %s/%s:\n    file: my-plugin.tar.gz\n", runtime.GOOS, runtime.GOARCH),
+			expectedError: "my-plugin.tar.gz: artifact file template has no ${version}",
+		},
+		{
+			scenario:        "latest",
+			metadata:        "name: my-plugin",
+			expectedPath:    "/src/my-plugin-1.3.1-" + platform + ".tar.gz",
+			expectedVersion: "1.3.1",
+		},
+		{
+			scenario:        "constraint",
+			metadata:        "name: my-plugin",
+			constraint:      ">=1.2 <1.3",
+			expectedPath:    "/src/my-plugin-1.2.0-" + platform + ".tar.gz",
+			expectedVersion: "1.2.0",
+		},
+		{
+			scenario:      "no matching version",
+			metadata:      "name: my-plugin",
+			constraint:    "^2",
+			expectedError: "^2: no matching version",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := newVersionsFs(t, tc.metadata, "1.2.0", "1.3.1", "1.1.0", "not-a-version")
+
+			if tc.metadata == "" {
+				require.NoError(t, fs.Remove("/src/.plugin.registry.yaml"))
+			}
+
+			path, v, err := ResolveArtifact(fs, "/src", tc.constraint)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPath, path)
+			assert.Equal(t, tc.expectedVersion, v.Original())
+		})
+	}
+}
+
+func TestInstallVersion(t *testing.T) {
+	t.Parallel()
+
+	fs := newVersionsFs(t, "name: my-plugin", "1.2.0", "1.3.1", "2.0.0")
+
+	p, err := InstallVersion(context.Background(), fs, "/app/plugins", "/src", "^1.2")
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.1", p.Version)
+
+	data, err := afero.ReadFile(fs, "/app/plugins/my-plugin/my-plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, "#!/bin/bash\n# 1.3.1\n", string(data))
+
+	plugins, err := NewFsInstaller(fs).List(context.Background(), "/app/plugins")
+	require.NoError(t, err)
+	require.Len(t, plugins, 1)
+
+	assert.Equal(t, "1.3.1", plugins[0].Version)
+
+	_, err = InstallVersion(context.Background(), fs, "/app/plugins", "/src", "^3")
+	require.EqualError(t, err, "could not resolve plugin version: ^3: no matching version")
+}
+
+func TestInstallVersion_MetadataVersion(t *testing.T) {
+	t.Parallel()
+
+	fs := newVersionsFs(t, "name: my-plugin\nversion: 0.1.0", "1.2.0", "1.3.1")
+
+	// The version in the shared metadata does not override the resolved version.
+	p, err := InstallVersion(context.Background(), fs, "/app/plugins", "/src", "~1.2")
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.2.0", p.Version)
+
+	r, err := readRecord(fs, "/app/plugins", "my-plugin")
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.2.0", r.Version)
+}
@@ -10,7 +10,6 @@ import (
 	"strings"
 
 	fsCtx "github.com/nhatthm/plugin-registry/context"
-	"github.com/nhatthm/plugin-registry/installer"
 	"github.com/nhatthm/plugin-registry/plugin"
 	"github.com/spf13/afero"
 )
@@ -18,21 +17,17 @@ import (
 // ErrPluginNotZip indicates that the plugin is not a zip.
 var ErrPluginNotZip = errors.New("plugin is not a zip")
 
-func init() { //nolint: gochecknoinits
-	installer.Register("zip", isZipPlugin, func(fs afero.Fs) installer.Installer {
-		return NewZipInstaller(fs)
-	})
-}
-
-// NewZipInstaller creates a new filesystem installer.
-func NewZipInstaller(fs afero.Fs) *ArchiveInstaller {
+// NewZipInstaller creates a new zip installer.
+func NewZipInstaller(fs afero.Fs, opts ...Option) *ArchiveInstaller {
 	i := &ArchiveInstaller{
-		fs: fs,
+		config: newConfig(fs, opts...),
 
 		parseURL: parseZipPath,
 		install:  installZip,
 	}
 
+	i.kind = "zip"
+
 	return i
 }
 
@@ -62,14 +57,17 @@ func parseZipPath(fs afero.Fs, path string) (string, string, error) { //nolint:
 	return path, metadataPath, nil
 }
 
-func installZip(fs afero.Fs, dst string, p plugin.Plugin, zipFile string) error {
-	fi, r, err := openPluginFile(fs, zipFile)
+func installZip(ctx context.Context, cfg *config, dst string, p plugin.Plugin, zipFile string) error {
+	fi, r, err := openPluginFile(cfg.srcFs, zipFile)
 	if err != nil {
 		return err
 	}
 	defer r.Close() //nolint: errcheck
 
-	zr, err := zip.NewReader(r, fi.Size())
+	// The bytes read from the archive are counted for every entry.
+	ra := &countingReaderAt{ReaderAt: r}
+
+	zr, err := zip.NewReader(ra, fi.Size())
 	if err != nil {
 		return err
 	}
@@ -77,40 +75,68 @@ func installZip(fs afero.Fs, dst string, p plugin.Plugin, zipFile string) error
 	pluginDir := fmt.Sprintf("%s%c", p.Name, os.PathSeparator)
 	dst = filepath.Join(dst, p.Name)
 
-	if err := recreatePath(fs, dst); err != nil {
+	if err := recreatePath(cfg.destFs, dst); err != nil {
 		return err
 	}
 
-	return extractZip(fs, dst, pluginDir, zr)
+	return extractZip(ctx, cfg, dst, pluginDir, zr, ra)
 }
 
-func extractZip(fs afero.Fs, dst, pluginDir string, zr *zip.Reader) error {
+// extractZip extracts the plugin files, ra is the archive that the zip reader reads from.
+func extractZip(ctx context.Context, cfg *config, dst, pluginDir string, zr *zip.Reader, ra *countingReaderAt) error {
+	extracted := entries{}
+
 	for _, f := range zr.File {
-		path := filepath.Join(dst, strings.TrimPrefix(f.Name, pluginDir))
+		name, ok := cfg.entryPath(pluginDir, f.Name)
+		if !ok || cfg.filter.skip(name, f.FileInfo().IsDir()) {
+			continue
+		}
+
+		path := filepath.Join(dst, name)
 
 		if !strings.HasPrefix(path, dst) {
-			return fmt.Errorf("%s: %w", path, ErrIllegalFilePath)
+			return entryError(f.Name, fmt.Errorf("%s: %w", path, ErrIllegalFilePath))
+		}
+
+		mode, err := cfg.modePolicy.apply(path, f.FileInfo().Mode())
+		if err != nil {
+			return entryError(f.Name, err)
 		}
 
 		if f.FileInfo().IsDir() {
-			if err := createPathIfNotExists(fs, path); err != nil {
-				return err
+			if err := createPathIfNotExists(cfg.destFs, path, mode); err != nil {
+				return entryError(f.Name, err)
 			}
 
 			continue
 		}
 
+		skip, err := extracted.skip(cfg.duplicatePolicy, path)
+		if err != nil {
+			return entryError(f.Name, err)
+		}
+
+		if skip {
+			continue
+		}
+
+		read := ra.n
+
 		src, err := f.Open()
 		if err != nil {
-			return err
+			return entryError(f.Name, err)
 		}
 
-		err = installStream(fs, path, src, f.FileInfo().Mode())
+		cr := &countingReader{Reader: src}
+		err = installStream(cfg.destFs, path, cr, mode)
 		_ = src.Close() //nolint: errcheck
 
 		if err != nil {
-			return err
+			return entryError(f.Name, err)
 		}
+
+		cfg.log(ctx).Debug(ctx, "extracted file", "entry", f.Name, "path", path, "mode", mode)
+		cfg.observer.ObserveEntry(ctx, f.Name, ra.n-read, cr.n)
 	}
 
 	return nil
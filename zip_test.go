@@ -276,6 +276,14 @@ func TestZipInstaller_Install_Error(t *testing.T) {
 				fs.On("Open", "/tmp/.plugin.registry.yaml").
 					Return(f, nil)
 
+				expectInstallLock(fs, "/app/plugins", "my-plugin")
+
+				fs.On("Stat", "/app/plugins/my-plugin").
+					Return(aferomock.NopFileInfo(t), nil)
+
+				fs.On("RemoveAll", "/app/plugins/.my-plugin.staging").
+					Return(nil)
+
 				fs.On("Open", "/tmp/my-plugin.zip").
 					Return(nil, errors.New("could not open zip file"))
 			}),
@@ -474,9 +482,9 @@ func TestInstallZip_Error(t *testing.T) {
 
 			dest := t.TempDir()
 
-			fs := tc.mockFs(t)
+			cfg := newConfig(tc.mockFs(t))
 			p := plugin.Plugin{Name: "my-plugin"}
-			err := installZip(fs, dest, p, tc.path)
+			err := installZip(context.Background(), &cfg, dest, p, tc.path)
 
 			if tc.expectedError == "" {
 				require.NoError(t, err)